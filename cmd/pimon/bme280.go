@@ -0,0 +1,208 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// BME280 registers and values, as given in the Bosch BME280 datasheet.
+const (
+	bme280Address = 0x76
+
+	bme280RegCalib1   = 0x88 // 26 bytes up to 0xA1
+	bme280RegChipID   = 0xD0
+	bme280RegCalib2   = 0xE1 // 7 bytes up to 0xE7
+	bme280RegCtrlHum  = 0xF2
+	bme280RegStatus   = 0xF3
+	bme280RegCtrlMeas = 0xF4
+	bme280RegData     = 0xF7 // 8 bytes up to 0xFE
+
+	bme280ChipID = 0x60
+
+	// Oversampling ×1 for humidity, temperature and pressure in forced mode.
+	bme280CtrlHum  = 0x01
+	bme280CtrlMeas = 0x01<<5 | 0x01<<2 | 0x01
+)
+
+// bme280Sensor reads temperature, humidity and pressure from a Bosch BME280
+// over I2C. Each read triggers a single measurement in forced mode.
+type bme280Sensor struct {
+	bus  I2CBus
+	n    int
+	addr byte
+	cal  bme280Calibration
+}
+
+type bme280Calibration struct {
+	T1         uint16
+	T2, T3     int16
+	P1         uint16
+	P2, P3, P4 int16
+	P5, P6, P7 int16
+	P8, P9     int16
+	H1, H3     uint8
+	H2, H4, H5 int16
+	H6         int8
+}
+
+func newBME280Sensor(sc SensorConfiguration) (Sensor, error) {
+	bus, err := openI2CBus(sc.I2CBus)
+	if err != nil {
+		return nil, err
+	}
+	return NewBME280Sensor(bus, sc.I2CBus, byte(sc.I2CAddress))
+}
+
+// NewBME280Sensor returns a driver for the BME280 at addr on bus, which has
+// the number n; if addr is zero, the default address 0x76 is used.
+func NewBME280Sensor(bus I2CBus, n int, addr byte) (Sensor, error) {
+	if addr == 0 {
+		addr = bme280Address
+	}
+
+	id := make([]byte, 1)
+	err := bus.ReadFromReg(addr, bme280RegChipID, id)
+	if err != nil {
+		return nil, err
+	}
+	if id[0] != bme280ChipID {
+		return nil, fmt.Errorf("device at address %#x is not a BME280 (chip id %#x)", addr, id[0])
+	}
+
+	c1 := make([]byte, 26)
+	err = bus.ReadFromReg(addr, bme280RegCalib1, c1)
+	if err != nil {
+		return nil, err
+	}
+	c2 := make([]byte, 7)
+	err = bus.ReadFromReg(addr, bme280RegCalib2, c2)
+	if err != nil {
+		return nil, err
+	}
+
+	le := binary.LittleEndian
+	s16 := func(b []byte) int16 { return int16(le.Uint16(b)) }
+	cal := bme280Calibration{
+		T1: le.Uint16(c1[0:]),
+		T2: s16(c1[2:]),
+		T3: s16(c1[4:]),
+		P1: le.Uint16(c1[6:]),
+		P2: s16(c1[8:]),
+		P3: s16(c1[10:]),
+		P4: s16(c1[12:]),
+		P5: s16(c1[14:]),
+		P6: s16(c1[16:]),
+		P7: s16(c1[18:]),
+		P8: s16(c1[20:]),
+		P9: s16(c1[22:]),
+		H1: c1[25],
+		H2: s16(c2[0:]),
+		H3: c2[2],
+		H4: int16(int8(c2[3]))<<4 | int16(c2[4]&0x0F),
+		H5: int16(int8(c2[5]))<<4 | int16(c2[4]>>4),
+		H6: int8(c2[6]),
+	}
+	return &bme280Sensor{bus, n, addr, cal}, nil
+}
+
+func (s *bme280Sensor) Read() (Reading, error) {
+	before := time.Now()
+
+	// The humidity control only takes effect after writing ctrl_meas.
+	err := s.bus.WriteByteToReg(s.addr, bme280RegCtrlHum, bme280CtrlHum)
+	if err != nil {
+		return Reading{}, err
+	}
+	err = s.bus.WriteByteToReg(s.addr, bme280RegCtrlMeas, bme280CtrlMeas)
+	if err != nil {
+		return Reading{}, err
+	}
+
+	// A measurement with oversampling ×1 takes at most 10ms.
+	status := make([]byte, 1)
+	retries := 0
+	for {
+		time.Sleep(10 * time.Millisecond)
+		err = s.bus.ReadFromReg(s.addr, bme280RegStatus, status)
+		if err != nil {
+			return Reading{}, err
+		}
+		if status[0]&0x08 == 0 {
+			break
+		}
+		retries++
+		if retries >= 10 {
+			return Reading{Retries: retries}, fmt.Errorf("measurement did not complete")
+		}
+	}
+
+	data := make([]byte, 8)
+	err = s.bus.ReadFromReg(s.addr, bme280RegData, data)
+	if err != nil {
+		return Reading{Retries: retries}, err
+	}
+	adcP := int32(data[0])<<12 | int32(data[1])<<4 | int32(data[2])>>4
+	adcT := int32(data[3])<<12 | int32(data[4])<<4 | int32(data[5])>>4
+	adcH := int32(data[6])<<8 | int32(data[7])
+
+	t, fine := s.cal.temperature(adcT)
+	return Reading{
+		Temperature: float32(t),
+		Humidity:    float32(s.cal.humidity(adcH, fine)),
+		Pressure:    float32(s.cal.pressure(adcP, fine) / 100),
+		Retries:     retries,
+		Duration:    time.Since(before),
+	}, nil
+}
+
+func (s *bme280Sensor) String() string { return fmt.Sprintf("BME280@%d:%#x", s.n, s.addr) }
+
+// The following compensation formulas are the floating point versions
+// from section 8.1 of the datasheet.
+
+// temperature returns the temperature in °C and the fine temperature
+// that is needed for compensating pressure and humidity.
+func (c bme280Calibration) temperature(adc int32) (float64, float64) {
+	v1 := (float64(adc)/16384 - float64(c.T1)/1024) * float64(c.T2)
+	v2 := float64(adc)/131072 - float64(c.T1)/8192
+	v2 = v2 * v2 * float64(c.T3)
+	fine := v1 + v2
+	return fine / 5120, fine
+}
+
+// pressure returns the pressure in Pa.
+func (c bme280Calibration) pressure(adc int32, fine float64) float64 {
+	v1 := fine/2 - 64000
+	v2 := v1 * v1 * float64(c.P6) / 32768
+	v2 = v2 + v1*float64(c.P5)*2
+	v2 = v2/4 + float64(c.P4)*65536
+	v1 = (float64(c.P3)*v1*v1/524288 + float64(c.P2)*v1) / 524288
+	v1 = (1 + v1/32768) * float64(c.P1)
+	if v1 == 0 {
+		return 0 // avoid division by zero
+	}
+	p := 1048576 - float64(adc)
+	p = (p - v2/4096) * 6250 / v1
+	v1 = float64(c.P9) * p * p / 2147483648
+	v2 = p * float64(c.P8) / 32768
+	return p + (v1+v2+float64(c.P7))/16
+}
+
+// humidity returns the relative humidity in %.
+func (c bme280Calibration) humidity(adc int32, fine float64) float64 {
+	h := fine - 76800
+	h = (float64(adc) - (float64(c.H4)*64 + float64(c.H5)/16384*h)) *
+		(float64(c.H2) / 65536 * (1 + float64(c.H6)/67108864*h*(1+float64(c.H3)/67108864*h)))
+	h = h * (1 - float64(c.H1)*h/524288)
+	if h > 100 {
+		return 100
+	} else if h < 0 {
+		return 0
+	}
+	return h
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// testBME280 is the calibration of the compensation example in the
+// datasheet, with the humidity trimming of a typical device.
+var testBME280 = bme280Calibration{
+	T1: 27504, T2: 26435, T3: -1000,
+	P1: 36477, P2: -10685, P3: 3024, P4: 2855, P5: 140, P6: -7,
+	P7: 15500, P8: -14600, P9: 6000,
+	H1: 75, H2: 362, H3: 0, H4: 313, H5: 50, H6: 30,
+}
+
+// The raw values of the compensation example in the datasheet, which
+// result in 25.08 °C and 100653.27 Pa.
+const (
+	testADCT = 519888
+	testADCP = 415148
+	testADCH = 30000
+)
+
+// The following are the fixed point compensation formulas from section
+// 4.2.3 of the datasheet, which the floating point versions must agree with.
+
+func bme280FineInt(c bme280Calibration, adc int32) int32 {
+	v1 := ((adc>>3 - int32(c.T1)<<1) * int32(c.T2)) >> 11
+	v2 := (((adc>>4 - int32(c.T1)) * (adc>>4 - int32(c.T1)) >> 12) * int32(c.T3)) >> 14
+	return v1 + v2
+}
+
+func bme280PressureInt(c bme280Calibration, adc, fine int32) float64 {
+	v1 := int64(fine) - 128000
+	v2 := v1 * v1 * int64(c.P6)
+	v2 += (v1 * int64(c.P5)) << 17
+	v2 += int64(c.P4) << 35
+	v1 = (v1*v1*int64(c.P3))>>8 + (v1*int64(c.P2))<<12
+	v1 = ((int64(1)<<47 + v1) * int64(c.P1)) >> 33
+	p := int64(1048576 - adc)
+	p = ((p<<31 - v2) * 3125) / v1
+	v1 = (int64(c.P9) * (p >> 13) * (p >> 13)) >> 25
+	v2 = (int64(c.P8) * p) >> 19
+	p = (p+v1+v2)>>8 + int64(c.P7)<<4
+	return float64(p) / 256
+}
+
+func bme280HumidityInt(c bme280Calibration, adc, fine int32) float64 {
+	v := fine - 76800
+	v = ((adc<<14 - int32(c.H4)<<20 - int32(c.H5)*v + 16384) >> 15) *
+		((((((v*int32(c.H6))>>10)*(((v*int32(c.H3))>>11)+32768))>>10+2097152)*int32(c.H2) + 8192) >> 14)
+	v -= (((v >> 15) * (v >> 15)) >> 7) * int32(c.H1) >> 4
+	if v < 0 {
+		v = 0
+	} else if v > 419430400 {
+		v = 419430400
+	}
+	return float64(v>>12) / 1024
+}
+
+func TestBME280Compensation(t *testing.T) {
+	c := testBME280
+	temp, fine := c.temperature(testADCT)
+	if math.Abs(temp-25.08) > 0.005 {
+		t.Errorf("temperature = %.4f °C, want 25.08 °C", temp)
+	}
+	if fi := bme280FineInt(c, testADCT); fi != 128422 || math.Abs(fine-float64(fi)) > 1 {
+		t.Errorf("fine temperature = %.1f, fixed point %d, want 128422", fine, fi)
+	}
+
+	p := c.pressure(testADCP, fine)
+	if math.Abs(p-100653.27) > 0.01 {
+		t.Errorf("pressure = %.2f Pa, want 100653.27 Pa", p)
+	}
+	if pi := bme280PressureInt(c, testADCP, int32(fine)); math.Abs(p-pi) > 5 {
+		t.Errorf("pressure = %.2f Pa, fixed point %.2f Pa", p, pi)
+	}
+
+	for _, adc := range []int32{25000, testADCH, 35000} {
+		h := c.humidity(adc, fine)
+		hi := bme280HumidityInt(c, adc, int32(fine))
+		if h <= 0 || h >= 100 || math.Abs(h-hi) > 0.05 {
+			t.Errorf("humidity(%d) = %.3f %%, fixed point %.3f %%", adc, h, hi)
+		}
+	}
+}
+
+// newFakeBME280 puts a BME280 with the calibration c at addr on bus,
+// which reports the raw values adcT, adcP and adcH.
+func newFakeBME280(bus *FakeI2CBus, addr byte, c bme280Calibration, adcT, adcP, adcH int32) {
+	regs := make(map[byte]byte)
+	put16 := func(reg byte, v uint16) {
+		var b [2]byte
+		binary.LittleEndian.PutUint16(b[:], v)
+		regs[reg], regs[reg+1] = b[0], b[1]
+	}
+	put16(0x88, c.T1)
+	for i, v := range []int16{c.T2, c.T3} {
+		put16(0x8A+byte(2*i), uint16(v))
+	}
+	put16(0x8E, c.P1)
+	for i, v := range []int16{c.P2, c.P3, c.P4, c.P5, c.P6, c.P7, c.P8, c.P9} {
+		put16(0x90+byte(2*i), uint16(v))
+	}
+	regs[0xA1] = c.H1
+	put16(0xE1, uint16(c.H2))
+	regs[0xE3] = c.H3
+	regs[0xE4] = byte(c.H4 >> 4)
+	regs[0xE5] = byte(c.H4&0x0F) | byte(c.H5&0x0F)<<4
+	regs[0xE6] = byte(c.H5 >> 4)
+	regs[0xE7] = byte(c.H6)
+
+	regs[bme280RegChipID] = bme280ChipID
+	put20 := func(reg byte, v int32) {
+		regs[reg], regs[reg+1], regs[reg+2] = byte(v>>12), byte(v>>4), byte(v<<4)
+	}
+	put20(0xF7, adcP)
+	put20(0xFA, adcT)
+	regs[0xFD], regs[0xFE] = byte(adcH>>8), byte(adcH)
+	bus.Registers[addr] = regs
+}
+
+func TestBME280Read(t *testing.T) {
+	bus := NewFakeI2CBus()
+	newFakeBME280(bus, 0x77, testBME280, testADCT, testADCP, testADCH)
+
+	if _, err := NewBME280Sensor(bus, 1, 0x76); err == nil {
+		t.Error("sensor at an empty address accepted")
+	}
+	s, err := NewBME280Sensor(bus, 1, 0x77)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.String() != "BME280@1:0x77" {
+		t.Errorf("String() = %q", s)
+	}
+
+	r, err := s.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, fine := testBME280.temperature(testADCT)
+	want := testBME280.humidity(testADCH, fine)
+	if math.Abs(float64(r.Temperature)-25.08) > 0.005 ||
+		math.Abs(float64(r.Pressure)-1006.5327) > 0.001 ||
+		math.Abs(float64(r.Humidity)-want) > 0.001 {
+		t.Errorf("Read() = %.2f °C, %.2f %%, %.4f hPa", r.Temperature, r.Humidity, r.Pressure)
+	}
+
+	// The humidity control must be written before the measurement control.
+	w := bus.Written[0x77]
+	if len(w) != 2 || w[0][0] != bme280RegCtrlHum || w[1][0] != bme280RegCtrlMeas {
+		t.Errorf("written %x, want ctrl_hum then ctrl_meas", w)
+	}
+
+	bus.Registers[0x77][bme280RegChipID] = 0x58
+	if _, err := NewBME280Sensor(bus, 1, 0x77); err == nil {
+		t.Error("BMP280 chip id accepted")
+	}
+}
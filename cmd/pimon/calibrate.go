@@ -0,0 +1,239 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/goulash/xdg"
+	"github.com/spf13/cobra"
+)
+
+// calibrationSuffix is where the calibrate command writes its results.
+// It is read as a configuration file after all the others.
+const calibrationSuffix = "pimon/calibration.toml"
+
+// Reference humidities of saturated salt solutions at 25°C,
+// sodium chloride and magnesium chloride respectively.
+const (
+	saltHigh = 75.3
+	saltLow  = 32.8
+)
+
+// Calibration corrects the readings of a sensor: each value is multiplied
+// by its scale and then the offset is added. A scale of zero is taken as 1.
+type Calibration struct {
+	TemperatureOffset float32 `toml:"temperature_offset"`
+	TemperatureScale  float32 `toml:"temperature_scale"`
+	HumidityOffset    float32 `toml:"humidity_offset"`
+	HumidityScale     float32 `toml:"humidity_scale"`
+}
+
+// Apply returns the calibrated temperature and humidity.
+func (c Calibration) Apply(t, rh float32) (float32, float32) {
+	return linear(t, c.TemperatureScale, c.TemperatureOffset), linear(rh, c.HumidityScale, c.HumidityOffset)
+}
+
+func linear(v, scale, offset float32) float32 {
+	if scale == 0 {
+		scale = 1
+	}
+	return scale*v + offset
+}
+
+// calibratedSensor applies a calibration to the readings of a sensor.
+type calibratedSensor struct {
+	Sensor
+	cal Calibration
+}
+
+// Calibrate returns s with the calibration configured for it applied,
+// or s itself if there is none.
+func Calibrate(s Sensor) Sensor {
+	cal, ok := Conf.Calibration[s.String()]
+	if !ok {
+		return s
+	}
+	return &calibratedSensor{s, cal}
+}
+
+func (s *calibratedSensor) Read() (Reading, error) {
+	r, err := s.Sensor.Read()
+	if err != nil {
+		return r, err
+	}
+	r.Temperature, r.Humidity = s.cal.Apply(r.Temperature, r.Humidity)
+	if r.Humidity < 0 {
+		r.Humidity = 0
+	} else if r.Humidity > 100 {
+		r.Humidity = 100
+	}
+	return r, nil
+}
+
+// Calibrate command {{{
+
+var (
+	calibrateInstrument  string
+	calibrateSamples     int
+	calibrateTemperature float64
+)
+
+var calibrateCmd = &cobra.Command{
+	Use:   "calibrate",
+	Short: "calibrate sensors with a salt test",
+	Long: `Calibrate the humidity of the sensors of an instrument with a two-point
+salt test. The sensors are placed in turn in a sealed container over a
+saturated solution of sodium chloride (75%) and of magnesium chloride (33%),
+and the scale and offset that map their readings onto these references are
+written to XDG_CONFIG_HOME/pimon/calibration.toml.
+
+  Calibrations can also be given in the configuration file, by the name
+  that the sensor has in the log:
+
+    [calibration."DHT22@4"]
+    humidity_offset = -3.5
+    humidity_scale = 1.02
+    temperature_offset = 0.4
+
+  If the true temperature during the test is given, the temperature offset
+  is calibrated as well.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ic, err := Conf.Instrument(calibrateInstrument)
+		exitIf(err)
+		var ss []Sensor
+		for _, sc := range ic.SensorConfigs() {
+			s, err := NewSensor(sc)
+			exitIf(err)
+			ss = append(ss, s)
+		}
+
+		in := bufio.NewReader(os.Stdin)
+		stage := func(name string, rh float64) []Reading {
+			fmt.Printf("Place the sensors over saturated %s (%.1f%%) in a sealed container\n", name, rh)
+			fmt.Printf("and press Enter once they have settled, which takes several hours.\n")
+			_, err := in.ReadString('\n')
+			exitIf(err)
+			rs := make([]Reading, len(ss))
+			for i, s := range ss {
+				rs[i], err = sampleSensor(s, calibrateSamples, Conf.Interval)
+				exitIf(err)
+				fmt.Printf("  %s: %.1f°C %.1f%%\n", s, rs[i].Temperature, rs[i].Humidity)
+			}
+			return rs
+		}
+		high := stage("sodium chloride", saltHigh)
+		low := stage("magnesium chloride", saltLow)
+
+		cals := make(map[string]Calibration)
+		for i, s := range ss {
+			c, err := twoPoint(high[i].Humidity, low[i].Humidity)
+			exitIf(err)
+			if calibrateTemperature != 0 {
+				t := (high[i].Temperature + low[i].Temperature) / 2
+				c.TemperatureOffset = float32(calibrateTemperature) - t
+			} else {
+				// Keep the temperature calibration that there is.
+				old := Conf.Calibration[s.String()]
+				c.TemperatureOffset, c.TemperatureScale = old.TemperatureOffset, old.TemperatureScale
+			}
+			fmt.Printf("%s: humidity_scale = %.3f, humidity_offset = %.2f, temperature_offset = %.2f\n",
+				s, c.HumidityScale, c.HumidityOffset, c.TemperatureOffset)
+			cals[s.String()] = c
+		}
+
+		file := xdg.UserConfig(calibrationSuffix)
+		exitIf(writeCalibrations(file, cals))
+		fmt.Printf("Calibration written to %s.\n", file)
+	},
+}
+
+func calibrateInit() {
+	calibrateCmd.Flags().StringVarP(&calibrateInstrument, "instrument", "I", "", "instrument whose sensors to calibrate")
+	calibrateCmd.Flags().IntVarP(&calibrateSamples, "samples", "n", 10, "number of readings per reference")
+	calibrateCmd.Flags().Float64VarP(&calibrateTemperature, "temperature", "t", 0, "true temperature during the test")
+}
+
+// sampleSensor reads s n times, every interval, and returns the median
+// of the readings. Failed reads are retried up to n times in total.
+func sampleSensor(s Sensor, n int, interval time.Duration) (Reading, error) {
+	if n < 1 {
+		n = 1
+	}
+	var ts, hs []float64
+	var lastErr error
+	for fails := 0; len(hs) < n; {
+		if len(hs) != 0 || fails != 0 {
+			time.Sleep(interval)
+		}
+		r, err := s.Read()
+		if err != nil {
+			lastErr = err
+			if fails++; fails >= n {
+				return Reading{}, fmt.Errorf("cannot read %s: %v", s, lastErr)
+			}
+			continue
+		}
+		ts = append(ts, float64(r.Temperature))
+		hs = append(hs, float64(r.Humidity))
+	}
+	return Reading{Temperature: float32(median64(ts)), Humidity: float32(median64(hs))}, nil
+}
+
+// twoPoint returns the humidity calibration that maps the readings high and
+// low taken over the reference salts onto the reference humidities.
+func twoPoint(high, low float32) (Calibration, error) {
+	// The negation also rejects readings that are not a number.
+	if !(high > low) {
+		return Calibration{}, fmt.Errorf("humidity over sodium chloride (%.1f%%) is not above that over magnesium chloride (%.1f%%)", high, low)
+	}
+	scale := (saltHigh - saltLow) / (high - low)
+	if scale < 0.5 || scale > 2 {
+		return Calibration{}, fmt.Errorf("humidity scale %.2f is implausible, the sensor may be faulty", scale)
+	}
+	return Calibration{
+		HumidityScale:  scale,
+		HumidityOffset: saltHigh - scale*high,
+	}, nil
+}
+
+// writeCalibrations merges cals into the calibrations in file.
+func writeCalibrations(file string, cals map[string]Calibration) error {
+	var cf struct {
+		Calibration map[string]Calibration `toml:"calibration"`
+	}
+	if _, err := os.Stat(file); err == nil {
+		if _, err := toml.DecodeFile(file, &cf); err != nil {
+			return fmt.Errorf("cannot read %s: %v", file, err)
+		}
+	}
+	if cf.Calibration == nil {
+		cf.Calibration = make(map[string]Calibration)
+	}
+	for k, c := range cals {
+		cf.Calibration[k] = c
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	err = toml.NewEncoder(f).Encode(cf)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// }}}
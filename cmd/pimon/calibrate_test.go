@@ -0,0 +1,77 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestTwoPoint(t *testing.T) {
+	nan := float32(math.NaN())
+	for _, c := range []struct {
+		high, low float32
+		err       bool
+	}{
+		{high: saltHigh, low: saltLow},
+		{high: 78.1, low: 36.4},
+		{high: 72.0, low: 30.0},
+		{high: 50, low: 50, err: true},
+		{high: 30, low: 75, err: true},
+		{high: 75, low: 74, err: true},
+		{high: 95, low: 5, err: true},
+		{high: nan, low: 33, err: true},
+		{high: 75, low: nan, err: true},
+	} {
+		cal, err := twoPoint(c.high, c.low)
+		if (err != nil) != c.err {
+			t.Errorf("twoPoint(%.1f, %.1f) error %v, want error %v", c.high, c.low, err, c.err)
+			continue
+		}
+		if c.err {
+			continue
+		}
+		if _, h := cal.Apply(20, c.high); math.Abs(float64(h-saltHigh)) > 1e-3 {
+			t.Errorf("twoPoint(%.1f, %.1f) maps the high reading to %.3f%%", c.high, c.low, h)
+		}
+		if _, l := cal.Apply(20, c.low); math.Abs(float64(l-saltLow)) > 1e-3 {
+			t.Errorf("twoPoint(%.1f, %.1f) maps the low reading to %.3f%%", c.high, c.low, l)
+		}
+		if tc, _ := cal.Apply(20, 50); tc != 20 {
+			t.Errorf("twoPoint(%.1f, %.1f) changes the temperature to %.1f", c.high, c.low, tc)
+		}
+	}
+}
+
+func TestCalibratedSensor(t *testing.T) {
+	s := &calibratedSensor{
+		Sensor: &FakeSensor{Readings: []Reading{{Temperature: 20, Humidity: 50}, {Temperature: 20, Humidity: 98}, {Temperature: 20, Humidity: 1}}},
+		cal:    Calibration{TemperatureOffset: 0.5, HumidityScale: 1.1, HumidityOffset: -3},
+	}
+	for _, want := range []Reading{{Temperature: 20.5, Humidity: 52}, {Temperature: 20.5, Humidity: 100}, {Temperature: 20.5, Humidity: 0}} {
+		r, err := s.Read()
+		if err != nil || r.Temperature != want.Temperature || math.Abs(float64(r.Humidity-want.Humidity)) > 1e-4 {
+			t.Errorf("Read() = %+v, %v, want %+v", r, err, want)
+		}
+	}
+
+	s.Sensor = &FakeSensor{Err: errors.New("checksum")}
+	if _, err := s.Read(); err == nil {
+		t.Error("Read() does not pass on the error of the sensor")
+	}
+}
+
+func TestSampleSensor(t *testing.T) {
+	s := &FakeSensor{Readings: []Reading{{Temperature: 20, Humidity: 50}, {Temperature: 30, Humidity: 90}, {Temperature: 21, Humidity: 51}}}
+	r, err := sampleSensor(s, 3, 0)
+	if err != nil || r.Temperature != 21 || r.Humidity != 51 {
+		t.Errorf("sampleSensor() = %+v, %v, want the median", r, err)
+	}
+
+	if _, err := sampleSensor(&FakeSensor{Err: errors.New("checksum")}, 3, 0); err == nil {
+		t.Error("sampleSensor() of a failing sensor does not fail")
+	}
+}
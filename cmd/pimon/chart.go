@@ -0,0 +1,139 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cassava/pillr/guitar"
+	"github.com/spf13/cobra"
+)
+
+const (
+	chartStep  = 5  // humidity per row in percent
+	chartTicks = 12 // hours between labels on the time axis
+	chartRows  = 100 / chartStep
+)
+
+// Chart renders a plain text chart of the humidity in s over the given number
+// of hours up to now. Each column is the mean humidity of one hour, and the
+// rows between low and high are marked as the safe range, unless low is not
+// below high, as when there is no temperature to compute it from.
+func (s Series) Chart(now time.Time, hours int, low, high float32) string {
+	if hours <= 0 {
+		return ""
+	}
+
+	var (
+		sum = make([]float32, hours)
+		cnt = make([]int, hours)
+	)
+	start := now.Add(-time.Duration(hours) * time.Hour).Unix()
+	for _, x := range s {
+		if x.UnixTime <= start || x.UnixTime > now.Unix() || math.IsNaN(float64(x.Humidity)) {
+			continue
+		}
+		i := int((x.UnixTime - start - 1) / 3600)
+		sum[i] += x.Humidity
+		cnt[i]++
+	}
+	rows := make([]int, hours)
+	empty := true
+	for i := range rows {
+		rows[i] = -1
+		if cnt[i] != 0 {
+			empty = false
+			mean := sum[i] / float32(cnt[i])
+			rows[i] = int(mean/chartStep + 0.5)
+			if rows[i] > chartRows {
+				rows[i] = chartRows
+			} else if rows[i] < 0 {
+				rows[i] = 0
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	for r := chartRows; r >= 0; r-- {
+		v := float32(r * chartStep)
+		safe := low < high && v >= low && v <= high
+		fmt.Fprintf(&buf, "%4d%% |", r*chartStep)
+		for _, c := range rows {
+			switch {
+			case c == r:
+				buf.WriteByte('*')
+			case safe:
+				buf.WriteByte('.')
+			default:
+				buf.WriteByte(' ')
+			}
+		}
+		buf.WriteByte('\n')
+	}
+
+	// Time axis with labels every chartTicks hours, counting back from now;
+	// labels that would run into the "now" label are left out.
+	fmt.Fprintf(&buf, "      +%s\n", strings.Repeat("-", hours))
+	axis := []byte(strings.Repeat(" ", hours+10))
+	for i := 0; i < hours; i += chartTicks {
+		label := fmt.Sprintf("-%dh", hours-i)
+		if i+len(label) >= hours-1 {
+			break
+		}
+		copy(axis[7+i:], label)
+	}
+	copy(axis[7+hours-1:], "now")
+	buf.Write(bytes.TrimRight(axis, " "))
+	buf.WriteByte('\n')
+	switch {
+	case empty:
+		fmt.Fprintf(&buf, "\n      no measurements in the last %d hours\n", hours)
+	case low < high:
+		fmt.Fprintf(&buf, "\n      * hourly mean humidity, . safe range (%.0f–%.0f%%)\n", low, high)
+	default:
+		fmt.Fprintf(&buf, "\n      * hourly mean humidity\n")
+	}
+	return buf.String()
+}
+
+// Chart command {{{
+
+var (
+	chartHours      int
+	chartInstrument string
+)
+
+var chartCmd = &cobra.Command{
+	Use:   "chart",
+	Short: "show humidity trend chart",
+	Long:  "Show a chart of the humidity in the last hours, as read from the database.",
+	Run: func(cmd *cobra.Command, args []string) {
+		ic, err := Conf.Instrument(chartInstrument)
+		exitIf(err)
+		g, err := guitar.Profile(ic.Profile)
+		exitIf(err)
+
+		s, err := ReadSeries(ic.Format, ic.DatabasePath())
+		exitIf(err)
+
+		var low, high float32
+		if s.Len() != 0 {
+			low, high = g.SafeHumidity(s.Top().Temperature)
+		}
+		fmt.Fprint(os.Stdout, s.Chart(time.Now(), chartHours, low, high))
+	},
+}
+
+func chartInit() {
+	chartCmd.Flags().IntVarP(&chartHours, "hours", "n", 48, "number of hours to show")
+	chartCmd.Flags().StringVarP(&chartInstrument, "instrument", "I", "", "instrument to show chart of")
+}
+
+// }}}
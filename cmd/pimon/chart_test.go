@@ -0,0 +1,82 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chartRow returns the columns of the row of the chart for humidity h.
+func chartRow(t *testing.T, chart string, h int) string {
+	lines := strings.Split(chart, "\n")
+	i := chartRows - h/chartStep
+	if i >= len(lines) || !strings.Contains(lines[i], "|") {
+		t.Fatalf("chart has no row for %d%%:\n%s", h, chart)
+	}
+	return lines[i][strings.Index(lines[i], "|")+1:]
+}
+
+func TestChartEmpty(t *testing.T) {
+	now := time.Date(2015, 11, 1, 12, 0, 0, 0, time.UTC)
+	for _, s := range []Series{nil, {{UnixTime: now.Add(-72 * time.Hour).Unix(), Humidity: 45}}} {
+		c := s.Chart(now, 24, 0, 0)
+		if strings.ContainsAny(c, "*.") || !strings.Contains(c, "no measurements in the last 24 hours") {
+			t.Errorf("chart of %d old measurements:\n%s", s.Len(), c)
+		}
+	}
+	if c := Series(nil).Chart(now, 0, 40, 55); c != "" {
+		t.Errorf("chart of no hours:\n%s", c)
+	}
+}
+
+func TestChartSinglePoint(t *testing.T) {
+	now := time.Date(2015, 11, 1, 12, 0, 0, 0, time.UTC)
+	s := Series{{UnixTime: now.Add(-90 * time.Minute).Unix(), Temperature: 21, Humidity: 46}}
+
+	for _, hours := range []int{1, 2, 3, 48} {
+		c := s.Chart(now, hours, 40, 55)
+		if hours == 1 {
+			if strings.Contains(c, "*") {
+				t.Errorf("chart of the last hour shows an older measurement:\n%s", c)
+			}
+			continue
+		}
+		want := strings.Repeat(".", hours-2) + "*."
+		if got := chartRow(t, c, 45); got != want {
+			t.Errorf("%d hour chart has row 45%% %q, want %q:\n%s", hours, got, want, c)
+		}
+		if got := chartRow(t, c, 60); strings.TrimSpace(got) != "" {
+			t.Errorf("%d hour chart marks 60%% as safe: %q", hours, got)
+		}
+		if strings.Count(c, "*") != 2 || !strings.Contains(c, "now") || !strings.Contains(c, "safe range (40–55%)") {
+			t.Errorf("%d hour chart:\n%s", hours, c)
+		}
+	}
+
+	// Without a safe range, only the measurement is shown.
+	c := s.Chart(now, 3, 0, 0)
+	if got := chartRow(t, c, 45); got != " * " || strings.Contains(c, ".") {
+		t.Errorf("chart without a safe range:\n%s", c)
+	}
+}
+
+func TestChartClamps(t *testing.T) {
+	now := time.Date(2015, 11, 1, 12, 0, 0, 0, time.UTC)
+	s := Series{
+		{UnixTime: now.Add(-150 * time.Minute).Unix(), Humidity: -3},
+		{UnixTime: now.Add(-90 * time.Minute).Unix(), Humidity: 104},
+		{UnixTime: now.Add(-30 * time.Minute).Unix(), Humidity: float32(math.NaN())},
+	}
+	c := s.Chart(now, 3, 40, 55)
+	if got := chartRow(t, c, 0); got != "*  " {
+		t.Errorf("row 0%% is %q:\n%s", got, c)
+	}
+	if got := chartRow(t, c, 100); got != " * " {
+		t.Errorf("row 100%% is %q:\n%s", got, c)
+	}
+}
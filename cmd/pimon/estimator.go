@@ -0,0 +1,251 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// EstimatorConfiguration selects and tunes the estimator that the monitor
+// uses to form its belief of the true climate from noisy measurements.
+type EstimatorConfiguration struct {
+	// Kind is either "ema", an exponential moving average, or "kalman".
+	Kind string `toml:"kind"`
+
+	// Lag is the weight of each new measurement in the moving average.
+	Lag float32 `toml:"lag"`
+
+	// TemperatureNoise and HumidityNoise are the standard deviations of the
+	// sensor noise, in °C and %RH. TemperatureDrift and HumidityDrift are
+	// the standard deviations of the change of the true values in an hour.
+	// These are only used by the Kalman filter.
+	TemperatureNoise float32 `toml:"temperature_noise"`
+	HumidityNoise    float32 `toml:"humidity_noise"`
+	TemperatureDrift float32 `toml:"temperature_drift"`
+	HumidityDrift    float32 `toml:"humidity_drift"`
+
+	// Reset is the time without measurements after which the estimate
+	// starts afresh from the next measurement, so that it is not biased
+	// towards what was measured before pimon or the sensor was down.
+	// If it is zero, the estimate is never reset.
+	Reset time.Duration `toml:"reset"`
+}
+
+var defaultEstimator = EstimatorConfiguration{
+	Kind:             "ema",
+	Lag:              0.1,
+	TemperatureNoise: 0.3,
+	HumidityNoise:    1.5,
+	TemperatureDrift: 1,
+	HumidityDrift:    2,
+	Reset:            time.Hour,
+}
+
+// Pressure is estimated with fixed noise and drift in hPa, as it hardly
+// matters for the danger.
+const (
+	pressureNoise = 0.5
+	pressureDrift = 1
+)
+
+// Estimator estimates the true climate from noisy measurements.
+type Estimator interface {
+	// Update incorporates the measurement x into the estimate.
+	Update(x Measurement)
+
+	// Belief returns the current estimate with its uncertainty.
+	Belief() Belief
+}
+
+// NewEstimator returns the estimator described by conf.
+func NewEstimator(conf EstimatorConfiguration) (Estimator, error) {
+	if conf.Reset < 0 {
+		return nil, fmt.Errorf("invalid estimator reset %v", conf.Reset)
+	}
+	switch conf.Kind {
+	case "ema":
+		if conf.Lag <= 0 || conf.Lag > 1 {
+			return nil, fmt.Errorf("invalid estimator lag %v", conf.Lag)
+		}
+		return &emaEstimator{lag: conf.Lag, reset: conf.Reset}, nil
+	case "kalman":
+		if conf.TemperatureNoise <= 0 || conf.HumidityNoise <= 0 {
+			return nil, fmt.Errorf("estimator noise must be positive")
+		}
+		return &kalmanEstimator{
+			reset:       conf.Reset,
+			temperature: kalman{q: sq(conf.TemperatureDrift), r: sq(conf.TemperatureNoise)},
+			humidity:    kalman{q: sq(conf.HumidityDrift), r: sq(conf.HumidityNoise)},
+			pressure:    kalman{q: sq(pressureDrift), r: sq(pressureNoise)},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown estimator %q, expecting ema or kalman", conf.Kind)
+	}
+}
+
+// Belief is the estimate of the true climate, where the uncertainties
+// are standard deviations of the temperature and humidity.
+type Belief struct {
+	Measurement
+	Estimator              string
+	TemperatureUncertainty float32
+	HumidityUncertainty    float32
+}
+
+func (b Belief) MarshalJSON() ([]byte, error) {
+	bs, err := b.Measurement.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	bs = bs[:len(bs)-1]
+	return append(bs, fmt.Sprintf(`, "estimator": %q, "temperature_uncertainty": %s, "humidity_uncertainty": %s}`,
+		b.Estimator, uncertainty(b.TemperatureUncertainty), uncertainty(b.HumidityUncertainty))...), nil
+}
+
+func (b Belief) String() string {
+	return fmt.Sprintf("%v (±%.2f C, ±%.2f%%)", b.Measurement, b.TemperatureUncertainty, b.HumidityUncertainty)
+}
+
+func uncertainty(f float32) string {
+	if math.IsInf(float64(f), 0) || math.IsNaN(float64(f)) {
+		return "null"
+	}
+	return strconv.FormatFloat(float64(f), 'f', 2, 32)
+}
+
+// EMA Estimator {{{
+
+// emaEstimator is an exponential moving average, which starts from the
+// first measurement. Its uncertainty is derived from the exponentially
+// weighted variance of the measurements around the average.
+type emaEstimator struct {
+	lag    float32
+	reset  time.Duration
+	init   bool
+	belief Measurement
+	tvar   float32
+	hvar   float32
+}
+
+func (e *emaEstimator) Update(x Measurement) {
+	if e.init && expired(e.belief.UnixTime, x.UnixTime, e.reset) {
+		*e = emaEstimator{lag: e.lag, reset: e.reset}
+	}
+	if !e.init {
+		e.belief, e.init = x, true
+		return
+	}
+	dt, dh := x.Temperature-e.belief.Temperature, x.Humidity-e.belief.Humidity
+	e.tvar = (1 - e.lag) * (e.tvar + e.lag*dt*dt)
+	e.hvar = (1 - e.lag) * (e.hvar + e.lag*dh*dh)
+
+	// Measurements without pressure leave the pressure as it is.
+	t, p := e.belief.UnixTime, e.belief.Pressure
+	e.belief.Update(e.lag, x)
+	switch {
+	case x.Pressure == 0:
+		e.belief.Pressure = p
+	case p == 0:
+		e.belief.Pressure = x.Pressure
+	}
+	if x.UnixTime < t {
+		e.belief.UnixTime = t
+	}
+}
+
+// expired returns whether the time from last to now, in seconds,
+// exceeds reset, if reset is not zero.
+func expired(last, now int64, reset time.Duration) bool {
+	return reset > 0 && time.Duration(now-last)*time.Second > reset
+}
+
+func (e *emaEstimator) Belief() Belief {
+	b := Belief{Measurement: e.belief, Estimator: "ema"}
+	if !e.init {
+		return b
+	}
+	// The variance of the average is that of the measurements times
+	// lag/(2-lag), if they are independent.
+	k := e.lag / (2 - e.lag)
+	b.TemperatureUncertainty = float32(math.Sqrt(float64(k * e.tvar)))
+	b.HumidityUncertainty = float32(math.Sqrt(float64(k * e.hvar)))
+	return b
+}
+
+// }}}
+
+// Kalman Estimator {{{
+
+// kalmanEstimator is a Kalman filter that models the temperature, humidity
+// and pressure each as a random walk, whose variance grows with the time
+// between measurements. Measurements that are older than the estimate are
+// incorporated as if they were taken at the time of the estimate.
+type kalmanEstimator struct {
+	time        int64
+	reset       time.Duration
+	temperature kalman
+	humidity    kalman
+	pressure    kalman
+}
+
+// kalman is a one-dimensional Kalman filter, with estimate x and variance p,
+// where q is the variance of the drift in an hour and r of the noise.
+type kalman struct {
+	x, p float64
+	q, r float64
+	init bool
+}
+
+func (k *kalman) update(z float64, hours float64) {
+	if !k.init {
+		k.x, k.p, k.init = z, k.r, true
+		return
+	}
+	k.p += k.q * hours
+	g := k.p / (k.p + k.r)
+	k.x += g * (z - k.x)
+	k.p *= 1 - g
+}
+
+func (e *kalmanEstimator) Update(x Measurement) {
+	if e.time != 0 && expired(e.time, x.UnixTime, e.reset) {
+		for _, k := range []*kalman{&e.temperature, &e.humidity, &e.pressure} {
+			k.x, k.p, k.init = 0, 0, false
+		}
+	}
+	var hours float64
+	if e.time != 0 && x.UnixTime > e.time {
+		hours = float64(x.UnixTime-e.time) / 3600
+	}
+	if x.UnixTime > e.time {
+		e.time = x.UnixTime
+	}
+	e.temperature.update(float64(x.Temperature), hours)
+	e.humidity.update(float64(x.Humidity), hours)
+	if x.Pressure != 0 {
+		e.pressure.update(float64(x.Pressure), hours)
+	}
+}
+
+func (e *kalmanEstimator) Belief() Belief {
+	return Belief{
+		Measurement: Measurement{
+			UnixTime:    e.time,
+			Temperature: float32(e.temperature.x),
+			Humidity:    float32(e.humidity.x),
+			Pressure:    float32(e.pressure.x),
+		},
+		Estimator:              "kalman",
+		TemperatureUncertainty: float32(math.Sqrt(e.temperature.p)),
+		HumidityUncertainty:    float32(math.Sqrt(e.humidity.p)),
+	}
+}
+
+// }}}
+
+func sq(f float32) float64 { return float64(f) * float64(f) }
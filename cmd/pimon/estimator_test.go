@@ -0,0 +1,55 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimatorReset(t *testing.T) {
+	for _, kind := range []string{"ema", "kalman"} {
+		conf := defaultEstimator
+		conf.Kind = kind
+		e, err := NewEstimator(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Update(Measurement{UnixTime: 0, Temperature: 15, Humidity: 70})
+		e.Update(Measurement{UnixTime: 60, Temperature: 15, Humidity: 70})
+
+		// A day later, the stale estimate must not bias the new one.
+		e.Update(Measurement{UnixTime: 86400, Temperature: 21, Humidity: 45})
+		if b := e.Belief(); b.Temperature != 21 || b.Humidity != 45 {
+			t.Errorf("%s: belief after a day without measurements is %s", kind, b)
+		}
+	}
+}
+
+func TestEstimatorBackwards(t *testing.T) {
+	conf := defaultEstimator
+	conf.Kind = "kalman"
+	e, _ := NewEstimator(conf)
+	e.Update(Measurement{UnixTime: 3600, Temperature: 21, Humidity: 45})
+	e.Update(Measurement{UnixTime: 60, Temperature: 21, Humidity: 46})
+	if b := e.Belief(); b.UnixTime != 3600 {
+		t.Errorf("time of belief went back to %d", b.UnixTime)
+	}
+	e.Update(Measurement{UnixTime: 3660, Temperature: 21, Humidity: 45})
+	if b := e.Belief(); b.HumidityUncertainty > 1.5 {
+		t.Errorf("uncertainty %.2f grew as if a long time had passed", b.HumidityUncertainty)
+	}
+}
+
+func TestEMAPressure(t *testing.T) {
+	e, _ := NewEstimator(defaultEstimator)
+	e.Update(Measurement{UnixTime: 0, Temperature: 21, Humidity: 45, Pressure: 1000})
+	for i := 1; i < 50; i++ {
+		e.Update(Measurement{UnixTime: int64(i) * int64(time.Minute/time.Second), Temperature: 21, Humidity: 45})
+	}
+	if p := e.Belief().Pressure; p != 1000 {
+		t.Errorf("pressure decayed to %.1f without pressure readings", p)
+	}
+}
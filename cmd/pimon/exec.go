@@ -0,0 +1,134 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// execTimeout is how long the command of an exec sensor may run
+// if no timeout is configured.
+const execTimeout = 30 * time.Second
+
+// execSensor runs a command for each reading, so that sensors that pimon
+// cannot read itself, such as Bluetooth hygrometers, can be plugged in.
+//
+// The command prints either a JSON object such as
+//
+//	{"temperature": 21.3, "humidity": 47.5, "pressure": 1013.2}
+//
+// where pressure and an RFC 3339 time are optional, or the temperature and
+// humidity, and optionally the pressure, separated by whitespace:
+//
+//	21.3 47.5
+type execSensor struct {
+	command []string
+	timeout time.Duration
+}
+
+func newExecSensor(sc SensorConfiguration) (Sensor, error) {
+	timeout := sc.Timeout
+	if timeout <= 0 {
+		timeout = execTimeout
+	}
+	return &execSensor{sc.Command, timeout}, nil
+}
+
+func (s *execSensor) Read() (Reading, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.command[0], s.command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	before := time.Now()
+	err := cmd.Run()
+	d := time.Since(before)
+	if ctx.Err() == context.DeadlineExceeded {
+		return Reading{Duration: d}, fmt.Errorf("command timed out after %s", s.timeout)
+	}
+	if err != nil {
+		if msg := firstLine(stderr.String()); msg != "" {
+			return Reading{Duration: d}, fmt.Errorf("command failed: %v: %s", err, msg)
+		}
+		return Reading{Duration: d}, fmt.Errorf("command failed: %v", err)
+	}
+
+	r, err := parseExecOutput(stdout.String())
+	r.Duration = d
+	return r, err
+}
+
+func (s *execSensor) String() string { return "exec:" + filepath.Base(s.command[0]) }
+
+// parseExecOutput parses the output of the command of an exec sensor.
+func parseExecOutput(out string) (Reading, error) {
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return Reading{}, errors.New("command printed nothing")
+	}
+
+	if strings.HasPrefix(out, "{") {
+		var v struct {
+			Time        time.Time `json:"time"`
+			Temperature *float32  `json:"temperature"`
+			Humidity    *float32  `json:"humidity"`
+			Pressure    float32   `json:"pressure"`
+		}
+		if err := json.Unmarshal([]byte(out), &v); err != nil {
+			return Reading{}, fmt.Errorf("cannot parse command output: %v", err)
+		}
+		if v.Temperature == nil || v.Humidity == nil {
+			return Reading{}, errors.New("command output lacks temperature or humidity")
+		}
+		return Reading{
+			Time:        v.Time,
+			Temperature: *v.Temperature,
+			Humidity:    *v.Humidity,
+			Pressure:    v.Pressure,
+		}, nil
+	}
+
+	fs := strings.Fields(firstLine(out))
+	if len(fs) != 2 && len(fs) != 3 {
+		return Reading{}, fmt.Errorf("cannot parse command output %q, expecting temperature and humidity", firstLine(out))
+	}
+	vs := make([]float32, len(fs))
+	for i, f := range fs {
+		v, err := strconv.ParseFloat(f, 32)
+		if err != nil {
+			return Reading{}, fmt.Errorf("cannot parse command output: %v", err)
+		}
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return Reading{}, fmt.Errorf("command output %q is not a number", f)
+		}
+		vs[i] = float32(v)
+	}
+	r := Reading{Temperature: vs[0], Humidity: vs[1]}
+	if len(vs) == 3 {
+		r.Pressure = vs[2]
+	}
+	return r, nil
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
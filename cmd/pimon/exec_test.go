@@ -0,0 +1,67 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseExecOutput(t *testing.T) {
+	at := time.Date(2015, 11, 1, 12, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		out  string
+		want Reading
+		err  bool
+	}{
+		{out: `{"temperature": 21.3, "humidity": 47.5}`, want: Reading{Temperature: 21.3, Humidity: 47.5}},
+		{out: `{"temperature": 21.3, "humidity": 47.5, "pressure": 1013.2, "time": "2015-11-01T12:00:00Z"}`,
+			want: Reading{Time: at, Temperature: 21.3, Humidity: 47.5, Pressure: 1013.2}},
+		{out: `{"temperature": 21.3, "humidity": 47.5, "battery": 80}` + "\n", want: Reading{Temperature: 21.3, Humidity: 47.5}},
+		{out: `{"temperature": 21.3}`, err: true},
+		{out: `{"humidity": 47.5}`, err: true},
+		{out: `{"temperature": "warm", "humidity": 47.5}`, err: true},
+		{out: `{"temperature": 21.3, "humidity": 47.5`, err: true},
+		{out: "21.3 47.5\n", want: Reading{Temperature: 21.3, Humidity: 47.5}},
+		{out: "  21.3\t47.5  1013.2 ", want: Reading{Temperature: 21.3, Humidity: 47.5, Pressure: 1013.2}},
+		{out: "21.3 47.5\nignored", want: Reading{Temperature: 21.3, Humidity: 47.5}},
+		{out: "21.3", err: true},
+		{out: "21.3 47.5 1013.2 3.3", err: true},
+		{out: "21.3 humid", err: true},
+		{out: "NaN 47.5", err: true},
+		{out: "21.3 +Inf", err: true},
+		{out: "temperature=21.3 humidity=47.5", err: true},
+		{out: "", err: true},
+		{out: " \n ", err: true},
+	} {
+		r, err := parseExecOutput(c.out)
+		if (err != nil) != c.err {
+			t.Errorf("parseExecOutput(%q) error %v, want error %v", c.out, err, c.err)
+			continue
+		}
+		if !c.err && (!r.Time.Equal(c.want.Time) || r.Temperature != c.want.Temperature ||
+			r.Humidity != c.want.Humidity || r.Pressure != c.want.Pressure) {
+			t.Errorf("parseExecOutput(%q) = %+v, want %+v", c.out, r, c.want)
+		}
+	}
+}
+
+func TestExecSensor(t *testing.T) {
+	s, _ := newExecSensor(SensorConfiguration{Command: []string{"sh", "-c", "echo 21.3 47.5"}})
+	if r, err := s.Read(); err != nil || r.Temperature != 21.3 || r.Humidity != 47.5 {
+		t.Errorf("Read() = %+v, %v", r, err)
+	}
+
+	s, _ = newExecSensor(SensorConfiguration{Command: []string{"sh", "-c", "echo no sensor >&2; exit 1"}})
+	if _, err := s.Read(); err == nil || !strings.Contains(err.Error(), "no sensor") {
+		t.Errorf("Read() of a failing command error %v, want its message", err)
+	}
+
+	s, _ = newExecSensor(SensorConfiguration{Command: []string{"sleep", "5"}, Timeout: 50 * time.Millisecond})
+	if _, err := s.Read(); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Read() of a slow command error %v, want a timeout", err)
+	}
+}
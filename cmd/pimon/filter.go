@@ -0,0 +1,188 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FilterConfiguration defines which readings are plausible. Sensors such as
+// the DHT22 occasionally return absurd values that still pass the checksum;
+// these are rejected before they reach the monitor.
+type FilterConfiguration struct {
+	// Readings outside of these limits are rejected.
+	MinTemperature float32 `toml:"min_temperature"`
+	MaxTemperature float32 `toml:"max_temperature"`
+	MinHumidity    float32 `toml:"min_humidity"`
+	MaxHumidity    float32 `toml:"max_humidity"`
+
+	// Readings that differ from the previous one by more than these steps
+	// per interval are rejected. A step of zero is not checked.
+	MaxTemperatureStep float32 `toml:"max_temperature_step"`
+	MaxHumidityStep    float32 `toml:"max_humidity_step"`
+
+	// MaxRejects is the number of consecutive readings that may be rejected
+	// because of their step, before they are taken to be real after all.
+	MaxRejects int `toml:"max_rejects"`
+
+	// Median is the number of readings whose median is passed on;
+	// if it is less than two, readings are passed on as they are.
+	Median int `toml:"median"`
+}
+
+var defaultFilter = FilterConfiguration{
+	MinTemperature:     -40,
+	MaxTemperature:     80,
+	MinHumidity:        0.5,
+	MaxHumidity:        99.5,
+	MaxTemperatureStep: 5,
+	MaxHumidityStep:    15,
+	MaxRejects:         3,
+	Median:             1,
+}
+
+// Filter rejects implausible readings and smooths the rest with a median.
+type Filter struct {
+	sync.Mutex
+
+	conf     FilterConfiguration
+	interval time.Duration
+
+	last     *Measurement
+	window   []Measurement
+	rejects  int
+	rejected int
+}
+
+// NewFilter returns a filter for readings that are taken every interval.
+func NewFilter(conf FilterConfiguration, interval time.Duration) *Filter {
+	return &Filter{conf: conf, interval: interval}
+}
+
+// Apply returns the measurement that should be passed on for x. If x is
+// rejected, an error describing why is returned instead.
+func (f *Filter) Apply(x Measurement) (Measurement, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	err := f.check(x)
+	if err != nil {
+		f.rejected++
+		return x, err
+	}
+
+	f.last = &x
+	if f.conf.Median < 2 {
+		return x, nil
+	}
+	f.window = append(f.window, x)
+	if len(f.window) > f.conf.Median {
+		f.window = f.window[len(f.window)-f.conf.Median:]
+	}
+	return median(f.window), nil
+}
+
+// Seed makes x the last measurement that the next one is checked against,
+// such as the last stored measurement when pimon starts.
+func (f *Filter) Seed(x Measurement) {
+	f.Lock()
+	defer f.Unlock()
+	f.last = &x
+}
+
+// Rejected returns the number of readings that were rejected so far.
+func (f *Filter) Rejected() int {
+	f.Lock()
+	defer f.Unlock()
+	return f.rejected
+}
+
+func (f *Filter) check(x Measurement) error {
+	c := f.conf
+	if x.Temperature < c.MinTemperature || x.Temperature > c.MaxTemperature {
+		return fmt.Errorf("temperature %.1f outside of %v to %v", x.Temperature, c.MinTemperature, c.MaxTemperature)
+	}
+	if x.Humidity < c.MinHumidity || x.Humidity > c.MaxHumidity {
+		return fmt.Errorf("humidity %.1f outside of %v to %v", x.Humidity, c.MinHumidity, c.MaxHumidity)
+	}
+	if f.last == nil {
+		return nil
+	}
+
+	// The allowed step grows with the time since the last reading.
+	n := float32(1)
+	if f.interval > 0 {
+		d := time.Duration(x.UnixTime-f.last.UnixTime) * time.Second
+		if k := float32(d) / float32(f.interval); k > 1 {
+			n = k
+		}
+	}
+	var err error
+	if dt := abs32(x.Temperature - f.last.Temperature); c.MaxTemperatureStep > 0 && dt > n*c.MaxTemperatureStep {
+		err = fmt.Errorf("temperature changed by %.1f", dt)
+	} else if dh := abs32(x.Humidity - f.last.Humidity); c.MaxHumidityStep > 0 && dh > n*c.MaxHumidityStep {
+		err = fmt.Errorf("humidity changed by %.1f", dh)
+	}
+	if err != nil {
+		f.rejects++
+		if f.rejects <= c.MaxRejects {
+			return err
+		}
+		// The change has persisted, so it is probably real.
+		f.window = nil
+	}
+	f.rejects = 0
+	return nil
+}
+
+// median returns the latest measurement in xs with the median temperature,
+// humidity and pressure of all of them. Only the measurements that have a
+// pressure count towards its median.
+func median(xs []Measurement) Measurement {
+	m := xs[len(xs)-1]
+	m.Temperature = median32(xs, func(x Measurement) float32 { return x.Temperature })
+	m.Humidity = median32(xs, func(x Measurement) float32 { return x.Humidity })
+
+	var ps []Measurement
+	for _, x := range xs {
+		if x.Pressure != 0 {
+			ps = append(ps, x)
+		}
+	}
+	m.Pressure = median32(ps, func(x Measurement) float32 { return x.Pressure })
+	return m
+}
+
+// median32 returns the median of the values of xs, or zero if there are none.
+func median32(xs []Measurement, value func(Measurement) float32) float32 {
+	if len(xs) == 0 {
+		return 0
+	}
+	vs := make([]float64, len(xs))
+	for i, x := range xs {
+		vs[i] = float64(value(x))
+	}
+	return float32(median64(vs))
+}
+
+// median64 returns the median of vs, which it sorts.
+func median64(vs []float64) float64 {
+	sort.Float64s(vs)
+	n := len(vs)
+	if n%2 == 1 {
+		return vs[n/2]
+	}
+	return (vs[n/2-1] + vs[n/2]) / 2
+}
+
+func abs32(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFilterSeed(t *testing.T) {
+	f := NewFilter(defaultFilter, time.Minute)
+	f.Seed(Measurement{UnixTime: 1000, Temperature: 21, Humidity: 45})
+	if _, err := f.Apply(Measurement{UnixTime: 1060, Temperature: 21, Humidity: 80}); err == nil {
+		t.Error("step from the seed accepted")
+	}
+	if _, err := f.Apply(Measurement{UnixTime: 1120, Temperature: 21, Humidity: 46}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFilterMedianPressure(t *testing.T) {
+	conf := defaultFilter
+	conf.Median = 3
+	f := NewFilter(conf, time.Minute)
+	var x Measurement
+	for i, p := range []float32{1010, 0, 1012} {
+		var err error
+		x, err = f.Apply(Measurement{UnixTime: int64(60 * i), Temperature: 21, Humidity: 45, Pressure: p})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if x.Pressure != 1011 {
+		t.Errorf("median pressure = %v, want 1011", x.Pressure)
+	}
+}
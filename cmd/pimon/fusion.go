@@ -0,0 +1,292 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// fusionRetry is how long a sensor stays out of rotation before
+	// it is tried again.
+	fusionRetry = 5 * time.Minute
+
+	// Readings that are further than these from the median of at least
+	// three sensors are outliers and are not fused.
+	fusionTemperatureTolerance = 2
+	fusionHumidityTolerance    = 5
+)
+
+// fusedSensor reads several sensors at the same location and fuses their
+// readings, so that a single bad sensor neither blinds nor misleads the
+// monitor. Sensors that keep failing or disagreeing with the others are
+// unhealthy and taken out of rotation until they are tried again after
+// fusionRetry.
+type fusedSensor struct {
+	sync.Mutex
+
+	mean    bool
+	members []*fusedMember
+	now     func() time.Time
+}
+
+type fusedMember struct {
+	Sensor
+	weight float64
+
+	healthy     bool
+	consecutive int
+	outliers    int
+	lastErr     string
+	lastSuccess time.Time
+	retryAt     time.Time
+}
+
+// NewInstrumentSensor returns the sensor of the instrument, which fuses
+// the readings of all its sensors if there are several.
+func NewInstrumentSensor(ic InstrumentConfiguration) (Sensor, error) {
+	scs := ic.SensorConfigs()
+	if len(scs) == 1 {
+		s, err := NewSensor(scs[0])
+		if err != nil {
+			return nil, err
+		}
+		return WithStats(Calibrate(s), time.Now), nil
+	}
+
+	ss := make([]Sensor, len(scs))
+	ws := make([]float64, len(scs))
+	for i, sc := range scs {
+		s, err := NewSensor(sc)
+		if err != nil {
+			return nil, err
+		}
+		ss[i], ws[i] = WithStats(Calibrate(s), time.Now), sc.Weight
+	}
+	return NewFusedSensor(ic.Fusion, ss, ws, time.Now)
+}
+
+// NewFusedSensor returns a sensor that fuses the readings of ss, either by
+// "median" or by weighted "mean", where ws contains the weights of ss
+// and now provides the clock.
+func NewFusedSensor(method string, ss []Sensor, ws []float64, now func() time.Time) (Sensor, error) {
+	if method != "median" && method != "mean" {
+		return nil, fmt.Errorf("unknown fusion %q, expecting median or mean", method)
+	}
+	if len(ss) == 0 {
+		return nil, errors.New("no sensors to fuse")
+	}
+	if len(ws) != len(ss) {
+		return nil, errors.New("number of weights and sensors differ")
+	}
+
+	f := &fusedSensor{mean: method == "mean", now: now}
+	for i, s := range ss {
+		w := ws[i]
+		if w == 0 {
+			w = 1
+		}
+		f.members = append(f.members, &fusedMember{Sensor: s, weight: w, healthy: true})
+	}
+	return f, nil
+}
+
+func (f *fusedSensor) Read() (Reading, error) {
+	now := f.now()
+	active := f.active(now)
+
+	// Slow sensors such as the DHT22 are read at the same time, and without
+	// holding the lock, so that their health can be reported meanwhile.
+	rs := make([]Reading, len(active))
+	errs := make([]error, len(active))
+	var wg sync.WaitGroup
+	for i, m := range active {
+		wg.Add(1)
+		go func(i int, m *fusedMember) {
+			defer wg.Done()
+			rs[i], errs[i] = m.Read()
+		}(i, m)
+	}
+	wg.Wait()
+
+	f.Lock()
+	defer f.Unlock()
+	var ok []int
+	var lastErr error
+	finished := 0
+	for i, m := range active {
+		if errs[i] == ErrReplayFinished {
+			finished++
+		}
+		if errs[i] != nil {
+			f.fail(m, now, errs[i])
+			lastErr = errs[i]
+			continue
+		}
+		ok = append(ok, i)
+	}
+
+	// With at least three readings, the median tells which ones are off.
+	if len(ok) >= 3 {
+		ts := make([]float64, len(ok))
+		hs := make([]float64, len(ok))
+		for j, i := range ok {
+			ts[j], hs[j] = float64(rs[i].Temperature), float64(rs[i].Humidity)
+		}
+		mt, mh := float32(median64(ts)), float32(median64(hs))
+		var agree []int
+		for _, i := range ok {
+			if abs32(rs[i].Temperature-mt) > fusionTemperatureTolerance || abs32(rs[i].Humidity-mh) > fusionHumidityTolerance {
+				active[i].outliers++
+				lastErr = fmt.Errorf("reading %.1f°C %.1f%% disagrees with the other sensors", rs[i].Temperature, rs[i].Humidity)
+				f.fail(active[i], now, lastErr)
+				continue
+			}
+			agree = append(agree, i)
+		}
+		ok = agree
+	}
+
+	if finished == len(active) {
+		return Reading{}, ErrReplayFinished
+	}
+	if len(ok) == 0 {
+		return Reading{}, fmt.Errorf("no sensor could be read: %v", lastErr)
+	}
+	readings := make([]Reading, len(ok))
+	weights := make([]float64, len(ok))
+	for j, i := range ok {
+		f.succeed(active[i], now)
+		readings[j], weights[j] = rs[i], active[i].weight
+	}
+	return f.fuse(readings, weights), nil
+}
+
+// active returns the sensors that are in rotation at now.
+func (f *fusedSensor) active(now time.Time) []*fusedMember {
+	f.Lock()
+	defer f.Unlock()
+	var active []*fusedMember
+	for _, m := range f.members {
+		if m.healthy || !now.Before(m.retryAt) {
+			active = append(active, m)
+		}
+	}
+	if len(active) == 0 {
+		// Trying the sensors is better than not reading anything at all.
+		active = f.members
+	}
+	return active
+}
+
+func (f *fusedSensor) fail(m *fusedMember, now time.Time, err error) {
+	m.consecutive++
+	m.lastErr = err.Error()
+	if m.consecutive < unhealthyFailures {
+		return
+	}
+	if m.healthy {
+		log.Warnf("sensor %s failed %d times in a row, taking it out of rotation: %s", m, m.consecutive, err)
+	}
+	m.healthy = false
+	m.retryAt = now.Add(fusionRetry)
+}
+
+func (f *fusedSensor) succeed(m *fusedMember, now time.Time) {
+	if !m.healthy {
+		log.Infof("sensor %s recovered, taking it back into rotation", m)
+	}
+	m.healthy = true
+	m.consecutive = 0
+	m.lastErr = ""
+	m.lastSuccess = now
+}
+
+// fuse returns a single reading for rs, by median or weighted mean.
+// Pressure is only fused from the readings that have one.
+func (f *fusedSensor) fuse(rs []Reading, ws []float64) Reading {
+	var r Reading
+	var ts, hs, ps, tws, pws []float64
+	for i, x := range rs {
+		if x.Time.After(r.Time) {
+			r.Time = x.Time
+		}
+		if x.Duration > r.Duration {
+			r.Duration = x.Duration
+		}
+		r.Retries += x.Retries
+		r.ChecksumFailures += x.ChecksumFailures
+		ts = append(ts, float64(x.Temperature))
+		hs = append(hs, float64(x.Humidity))
+		tws = append(tws, ws[i])
+		if x.Pressure != 0 {
+			ps = append(ps, float64(x.Pressure))
+			pws = append(pws, ws[i])
+		}
+	}
+
+	if f.mean {
+		r.Temperature = float32(mean64(ts, tws))
+		r.Humidity = float32(mean64(hs, tws))
+		if len(ps) != 0 {
+			r.Pressure = float32(mean64(ps, pws))
+		}
+	} else {
+		r.Temperature = float32(median64(ts))
+		r.Humidity = float32(median64(hs))
+		if len(ps) != 0 {
+			r.Pressure = float32(median64(ps))
+		}
+	}
+	return r
+}
+
+// Health returns the health of each of the fused sensors, where a sensor
+// that disagrees with the others is unhealthy even if it can be read.
+func (f *fusedSensor) Health() []SensorHealth {
+	f.Lock()
+	defer f.Unlock()
+	hs := make([]SensorHealth, len(f.members))
+	for i, m := range f.members {
+		h := SensorHealth{Sensor: m.String()}
+		if hr, ok := m.Sensor.(HealthReporter); ok {
+			h = hr.Health()[0]
+		}
+		h.Healthy = m.healthy
+		h.Consecutive = m.consecutive
+		h.Outliers = m.outliers
+		h.LastError = m.lastErr
+		h.LastSuccess = m.lastSuccess
+		hs[i] = h
+	}
+	return hs
+}
+
+func (f *fusedSensor) String() string {
+	names := make([]string, len(f.members))
+	for i, m := range f.members {
+		names[i] = m.String()
+	}
+	method := "median"
+	if f.mean {
+		method = "mean"
+	}
+	return fmt.Sprintf("%s(%s)", method, strings.Join(names, ","))
+}
+
+func mean64(vs, ws []float64) float64 {
+	var sum, n float64
+	for i, v := range vs {
+		sum += ws[i] * v
+		n += ws[i]
+	}
+	return sum / n
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// blockingSensor is a sensor whose reads only return once they are released.
+type blockingSensor struct {
+	reading chan Reading
+	started chan struct{}
+}
+
+func newBlockingSensor() *blockingSensor {
+	return &blockingSensor{reading: make(chan Reading), started: make(chan struct{}, 16)}
+}
+
+func (s *blockingSensor) Read() (Reading, error) {
+	s.started <- struct{}{}
+	return <-s.reading, nil
+}
+
+func (s *blockingSensor) String() string { return "blocking" }
+
+func TestFusedHealthDuringRead(t *testing.T) {
+	slow := newBlockingSensor()
+	fast := &FakeSensor{Readings: []Reading{{Temperature: 21, Humidity: 50}}}
+	fs, err := NewFusedSensor("mean", []Sensor{slow, fast}, []float64{1, 1}, time.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan Reading)
+	go func() {
+		r, err := fs.Read()
+		if err != nil {
+			t.Error(err)
+		}
+		done <- r
+	}()
+	<-slow.started
+
+	health := make(chan []SensorHealth)
+	go func() { health <- fs.(HealthReporter).Health() }()
+	select {
+	case hs := <-health:
+		if len(hs) != 2 {
+			t.Errorf("health of %d sensors, want 2", len(hs))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("health blocked by a slow read")
+	}
+
+	slow.reading <- Reading{Temperature: 23, Humidity: 46}
+	if r := <-done; r.Temperature != 22 || r.Humidity != 48 {
+		t.Errorf("fused reading %.1f°C %.1f%%, want 22°C 48%%", r.Temperature, r.Humidity)
+	}
+}
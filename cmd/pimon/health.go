@@ -0,0 +1,109 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrChecksum is returned by sensors whose data fails the checksum.
+var ErrChecksum = errors.New("checksum mismatch")
+
+// unhealthyFailures is the number of consecutive failed or outlying
+// readings after which a sensor is considered unhealthy.
+const unhealthyFailures = 3
+
+// SensorHealth describes how well a sensor has been working, so that
+// a dying sensor can be spotted before it fails completely.
+type SensorHealth struct {
+	Sensor  string `json:"sensor"`
+	Healthy bool   `json:"healthy"`
+
+	// Attempts is the number of reads, of which Failures failed. Retries
+	// is the number of times the driver had to retry in total, and
+	// ChecksumFailures the number of attempts, retried or not, whose data
+	// failed the checksum.
+	Attempts         int `json:"attempts"`
+	Failures         int `json:"failures"`
+	ChecksumFailures int `json:"checksum_failures"`
+	Retries          int `json:"retries"`
+
+	// Consecutive is the number of consecutive failed or outlying readings,
+	// and Outliers the number of readings that disagreed with other sensors.
+	Consecutive int `json:"consecutive_failures"`
+	Outliers    int `json:"outliers"`
+
+	// MeanLatency and MaxLatency are how long reads took, in seconds.
+	MeanLatency float64 `json:"mean_latency"`
+	MaxLatency  float64 `json:"max_latency"`
+
+	LastError   string    `json:"last_error,omitempty"`
+	LastSuccess time.Time `json:"last_success"`
+}
+
+// HealthReporter is implemented by sensors that keep track of their health,
+// or of the health of the sensors they consist of.
+type HealthReporter interface {
+	Health() []SensorHealth
+}
+
+// statsSensor keeps track of the health of a sensor.
+type statsSensor struct {
+	Sensor
+
+	mu      sync.Mutex
+	health  SensorHealth
+	latency time.Duration
+	now     func() time.Time
+}
+
+// WithStats returns s, keeping track of its health.
+func WithStats(s Sensor, now func() time.Time) Sensor {
+	return &statsSensor{
+		Sensor: s,
+		health: SensorHealth{Sensor: s.String(), Healthy: true},
+		now:    now,
+	}
+}
+
+func (s *statsSensor) Read() (Reading, error) {
+	before := s.now()
+	r, err := s.Sensor.Read()
+	d := s.now().Sub(before)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := &s.health
+	h.Attempts++
+	h.Retries += r.Retries
+	h.ChecksumFailures += r.ChecksumFailures
+	s.latency += d
+	h.MeanLatency = (s.latency / time.Duration(h.Attempts)).Seconds()
+	if d.Seconds() > h.MaxLatency {
+		h.MaxLatency = d.Seconds()
+	}
+	if err != nil {
+		h.Failures++
+		if err == ErrChecksum {
+			h.ChecksumFailures++
+		}
+		h.Consecutive++
+		h.LastError = err.Error()
+	} else {
+		h.Consecutive = 0
+		h.LastError = ""
+		h.LastSuccess = s.now()
+	}
+	h.Healthy = h.Consecutive < unhealthyFailures
+	return r, err
+}
+
+func (s *statsSensor) Health() []SensorHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return []SensorHealth{s.health}
+}
@@ -5,25 +5,32 @@
 package main
 
 import (
-	"time"
-
-	log "github.com/Sirupsen/logrus"
 	"github.com/cassava/pillr/guitar"
 	"github.com/cassava/pillr/led"
-	"github.com/d2r2/go-dht"
 )
 
+// newLED returns the LED on pin, using the backend in the configuration.
+func newLED(pin int) *led.LED {
+	if Conf.LED == "fake" {
+		return led.NewWithPin(&led.FakePin{})
+	}
+	return led.New(pin)
+}
+
 type WarningLED struct {
 	LED    *led.LED
 	Threat guitar.Danger
+
+	failed bool
 }
 
 func (wl *WarningLED) Update(d guitar.Danger) {
-	if wl.Threat == d {
+	if wl.Threat == d && !wl.failed {
 		return
 	}
 
 	wl.Threat = d
+	wl.failed = false
 	p := Conf.Patterns.Get(d)
 	if len(p) < 2 {
 		wl.LED.Stop()
@@ -33,34 +40,8 @@ func (wl *WarningLED) Update(d guitar.Danger) {
 	wl.LED.Blink(p...)
 }
 
-func WatchSensor(pin int, done <-chan struct{}, f func(Measurement)) {
-	ch := make(chan Measurement, 1)
-
-	read := func() {
-		before := time.Now()
-		after := time.Now()
-		var m Measurement
-		for after.Sub(before) <= Conf.Interval {
-			t, h, r, err := dht.ReadDHTxxWithRetry(dht.DHT22, pin, false, 10)
-			if err != nil {
-				log.WithFields(log.Fields{"retries": r}).Errorf("DHT22: %s", err)
-				continue
-			}
-			log.WithFields(log.Fields{"retries": r}).Debugf("DHT22: temp=%v humidity=%v", t, h)
-
-			after = time.Now()
-			m = Measurement{after.Unix(), t, h}
-		}
-		ch <- m
-	}
-
-	for {
-		go read()
-		select {
-		case <-done:
-			return
-		case x := <-ch:
-			f(x)
-		}
-	}
+// Fail shows the failure pattern until the next update.
+func (wl *WarningLED) Fail() {
+	wl.failed = true
+	wl.LED.Blink(Conf.Patterns.Failure...)
 }
@@ -0,0 +1,55 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cassava/pillr/guitar"
+	"github.com/cassava/pillr/led"
+)
+
+func TestWarningLED(t *testing.T) {
+	defer func(c Configuration) { *Conf = c }(*Conf)
+	Conf.Patterns.High = []time.Duration{time.Millisecond, time.Millisecond}
+	Conf.Patterns.Failure = []time.Duration{time.Hour, time.Hour}
+
+	pin := &led.FakePin{}
+	wl := &WarningLED{LED: led.NewWithPin(pin), Threat: guitar.Low}
+	defer wl.LED.Stop()
+
+	// The failure pattern is shown even though the threat is low.
+	wl.Fail()
+	waitFor(t, "failure pattern", pin.On)
+	if wl.Threat != guitar.Low {
+		t.Errorf("threat after a failure is %v, want low", wl.Threat)
+	}
+
+	// An update with the same threat ends it.
+	wl.Update(guitar.Low)
+	if pin.On() {
+		t.Error("LED still on after the failure was cleared")
+	}
+	n := pin.Toggles()
+	wl.Update(guitar.Low)
+	if pin.Toggles() != n {
+		t.Error("LED changed although the threat did not")
+	}
+
+	// A failure while in danger replaces the pattern of the danger,
+	// and the next update brings it back.
+	wl.Update(guitar.High)
+	waitFor(t, "high pattern", func() bool { return pin.Toggles() > n+2 })
+	wl.Fail()
+	waitFor(t, "failure pattern", pin.On)
+	n = pin.Toggles()
+	time.Sleep(20 * time.Millisecond)
+	if pin.Toggles() != n {
+		t.Error("LED still blinks the high pattern after a failure")
+	}
+	wl.Update(guitar.High)
+	waitFor(t, "high pattern", func() bool { return pin.Toggles() > n+2 })
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/kidoman/embd"
+)
+
+// I2CBus is the part of embd.I2CBus that the I2C sensor drivers need.
+// It lets the drivers be used with a FakeI2CBus without any hardware.
+type I2CBus interface {
+	ReadBytes(addr byte, num int) ([]byte, error)
+	WriteBytes(addr byte, value []byte) error
+	ReadFromReg(addr, reg byte, value []byte) error
+	WriteByteToReg(addr, reg, value byte) error
+}
+
+var (
+	i2cOnce  sync.Once
+	i2cErr   error
+	i2cBuses = make(map[int]I2CBus)
+	i2cMutex sync.Mutex
+)
+
+// openI2CBus returns the I2C bus with the given number, initializing
+// I2C on first use. Buses are shared between all sensors.
+func openI2CBus(n int) (I2CBus, error) {
+	i2cOnce.Do(func() { i2cErr = embd.InitI2C() })
+	if i2cErr != nil {
+		return nil, i2cErr
+	}
+
+	i2cMutex.Lock()
+	defer i2cMutex.Unlock()
+	bus, ok := i2cBuses[n]
+	if !ok {
+		bus = embd.NewI2CBus(byte(n))
+		i2cBuses[n] = bus
+	}
+	return bus, nil
+}
+
+// closeI2C closes I2C if it was initialized by openI2CBus.
+func closeI2C() {
+	i2cMutex.Lock()
+	defer i2cMutex.Unlock()
+	if len(i2cBuses) != 0 {
+		embd.CloseI2C()
+	}
+}
+
+// FakeI2CBus simulates devices on an I2C bus. Registers holds the contents
+// of the registers of each device by address, and Responses the bytes that
+// a device returns when it is read from without a register.
+// All writes are recorded in Written.
+type FakeI2CBus struct {
+	sync.Mutex
+
+	Registers map[byte]map[byte]byte
+	Responses map[byte][]byte
+	Written   map[byte][][]byte
+}
+
+func NewFakeI2CBus() *FakeI2CBus {
+	return &FakeI2CBus{
+		Registers: make(map[byte]map[byte]byte),
+		Responses: make(map[byte][]byte),
+		Written:   make(map[byte][][]byte),
+	}
+}
+
+func (b *FakeI2CBus) ReadBytes(addr byte, num int) ([]byte, error) {
+	b.Lock()
+	defer b.Unlock()
+	r, ok := b.Responses[addr]
+	if !ok {
+		return nil, fmt.Errorf("no device at address %#x", addr)
+	}
+	if len(r) < num {
+		return nil, fmt.Errorf("device at address %#x returned %d of %d bytes", addr, len(r), num)
+	}
+	return append([]byte(nil), r[:num]...), nil
+}
+
+func (b *FakeI2CBus) WriteBytes(addr byte, value []byte) error {
+	b.Lock()
+	defer b.Unlock()
+	b.Written[addr] = append(b.Written[addr], append([]byte(nil), value...))
+	return nil
+}
+
+func (b *FakeI2CBus) ReadFromReg(addr, reg byte, value []byte) error {
+	b.Lock()
+	defer b.Unlock()
+	regs, ok := b.Registers[addr]
+	if !ok {
+		return fmt.Errorf("no device at address %#x", addr)
+	}
+	for i := range value {
+		value[i] = regs[reg+byte(i)]
+	}
+	return nil
+}
+
+func (b *FakeI2CBus) WriteByteToReg(addr, reg, value byte) error {
+	b.Lock()
+	defer b.Unlock()
+	b.Written[addr] = append(b.Written[addr], []byte{reg, value})
+	if regs, ok := b.Registers[addr]; ok {
+		regs[reg] = value
+	}
+	return nil
+}
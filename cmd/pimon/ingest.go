@@ -0,0 +1,207 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// IngestConfiguration configures the endpoint that remote sensors, such as
+// microcontrollers in other rooms, post their measurements to.
+type IngestConfiguration struct {
+	// Token must be sent by remote sensors as a bearer token in the
+	// Authorization header. If it is empty, the endpoint is disabled.
+	Token string `toml:"token"`
+}
+
+const (
+	// ingestMaxBody is the maximum size of a request in bytes.
+	ingestMaxBody = 1 << 20
+
+	// ingestMaxSkew is how far in the future a measurement may be,
+	// to allow for clocks that are not quite synchronized.
+	ingestMaxSkew = time.Minute
+)
+
+// ingestRecord is a measurement as posted by a remote sensor, where Sensor
+// is the id of the sensor or the name of the instrument.
+type ingestRecord struct {
+	Sensor      string     `json:"sensor"`
+	Time        *time.Time `json:"time"`
+	Temperature *float32   `json:"temperature"`
+	Humidity    *float32   `json:"humidity"`
+	Pressure    float32    `json:"pressure"`
+}
+
+// serveIngest accepts measurements from remote sensors. The body is either
+// a single JSON object or an array of them, such as
+//
+//	{"sensor": "attic", "temperature": 21.3, "humidity": 47.5}
+//
+// where time (RFC 3339) and pressure are optional. A malformed request is
+// rejected as a whole. Measurements must be in order and newer than the last
+// measurement of their instrument; if one is rejected nonetheless, those
+// before it have been accepted and those after it have not.
+func serveIngest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	if Conf.Ingest.Token == "" {
+		http.Error(w, "ingest disabled", 404)
+		return
+	}
+	if !authorized(r, Conf.Ingest.Token) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="pimon"`)
+		http.Error(w, "unauthorized", 401)
+		return
+	}
+
+	bs, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, ingestMaxBody))
+	if err != nil {
+		http.Error(w, err.Error(), 413)
+		return
+	}
+	rs, err := parseIngest(bs)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	now := time.Now()
+	ins := make([]*Instrument, len(rs))
+	xs := make([]Measurement, len(rs))
+	last := make(map[*Instrument]int64)
+	for i, rec := range rs {
+		in := ingestInstrument(rec.Sensor)
+		if in == nil {
+			http.Error(w, fmt.Sprintf("measurement %d: unknown sensor %q", i, rec.Sensor), 404)
+			return
+		}
+		x, err := rec.measurement(now)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("measurement %d: %s", i, err), 400)
+			return
+		}
+		if t, ok := last[in]; ok && x.UnixTime <= t {
+			http.Error(w, fmt.Sprintf("measurement %d: not newer than the one before", i), 400)
+			return
+		}
+		last[in] = x.UnixTime
+		ins[i], xs[i] = in, x
+	}
+
+	for i, in := range ins {
+		if err := in.Ingest(xs[i]); err != nil {
+			log.WithFields(log.Fields{
+				"instrument": in.Name,
+				"sensor":     rs[i].Sensor,
+			}).Warnf("rejecting ingested %s: %s", xs[i], err)
+			http.Error(w, fmt.Sprintf("measurement %d rejected, %d accepted: %s", i, i, err), 409)
+			return
+		}
+	}
+	w.WriteHeader(204)
+}
+
+// authorized returns whether r carries token as its bearer token.
+func authorized(r *http.Request, token string) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	got := strings.TrimSpace(auth[len("Bearer "):])
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// parseIngest parses a single record or an array of records.
+func parseIngest(bs []byte) ([]ingestRecord, error) {
+	bs = bytes.TrimSpace(bs)
+	var rs []ingestRecord
+	var err error
+	if len(bs) != 0 && bs[0] == '[' {
+		err = json.Unmarshal(bs, &rs)
+	} else {
+		rs = make([]ingestRecord, 1)
+		err = json.Unmarshal(bs, &rs[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("malformed measurement: %v", err)
+	}
+	if len(rs) == 0 {
+		return nil, errors.New("no measurements")
+	}
+	return rs, nil
+}
+
+// measurement returns the measurement of the record, which is taken at now
+// if the record has no time.
+func (rec ingestRecord) measurement(now time.Time) (Measurement, error) {
+	if rec.Temperature == nil || rec.Humidity == nil {
+		return Measurement{}, errors.New("temperature or humidity missing")
+	}
+	for _, v := range []float32{*rec.Temperature, *rec.Humidity, rec.Pressure} {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return Measurement{}, errors.New("value is not a number")
+		}
+	}
+	t := now
+	if rec.Time != nil {
+		t = *rec.Time
+		if t.After(now.Add(ingestMaxSkew)) {
+			return Measurement{}, errors.New("time is in the future")
+		}
+	}
+	return Measurement{
+		UnixTime:    t.Unix(),
+		Temperature: *rec.Temperature,
+		Humidity:    *rec.Humidity,
+		Pressure:    rec.Pressure,
+	}, nil
+}
+
+// ingestInstrument returns the instrument that the remote sensor with the
+// given id measures, or nil if there is none.
+func ingestInstrument(id string) *Instrument {
+	for _, in := range instruments {
+		for _, n := range in.Nodes {
+			if n == id {
+				return in
+			}
+		}
+	}
+	for _, in := range instruments {
+		if in.Name == id {
+			return in
+		}
+	}
+	return nil
+}
+
+// remoteSensor stands in for the sensor of an instrument that is only
+// measured by remote sensors; it cannot be read.
+type remoteSensor struct{}
+
+func newRemoteSensor(sc SensorConfiguration) (Sensor, error) {
+	return remoteSensor{}, nil
+}
+
+func (remoteSensor) Read() (Reading, error) {
+	return Reading{}, errors.New("remote sensors post their measurements to /ingest")
+}
+
+func (remoteSensor) String() string { return "remote" }
@@ -0,0 +1,79 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIngestOrder(t *testing.T) {
+	defer func(c Configuration) { *Conf = c }(*Conf)
+	Conf.Mail = MailConfiguration{}
+	Conf.LED = "fake"
+
+	dir, err := ioutil.TempDir("", "pimon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ic := InstrumentConfiguration{
+		Name:     "test",
+		Profile:  "larrivee",
+		Database: filepath.Join(dir, "test.csv"),
+		Format:   "csv",
+		Sensor:   "fake",
+		Nodes:    []string{"a", "b"},
+	}
+
+	// The database already holds a measurement from before pimon started.
+	p, err := NewPersister(ic.Format, ic.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	p.Persist(Measurement{UnixTime: now - 1000, Temperature: 21, Humidity: 45})
+	p.Close()
+
+	in, err := NewInstrument(ic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	x := func(t int64) Measurement { return Measurement{UnixTime: now - 2000 + t, Temperature: 21, Humidity: 45} }
+	for _, c := range []struct {
+		local bool
+		t     int64
+		ok    bool
+	}{
+		{false, 1000, false}, // not newer than the database
+		{false, 1010, true},
+		{false, 1005, false}, // a lagging node
+		{true, 1020, true},
+		{false, 1015, false}, // lagging behind the local sensor
+		{false, 1030, true},
+	} {
+		if c.local {
+			in.Update(x(c.t))
+		} else if err := in.Ingest(x(c.t)); (err == nil) != c.ok {
+			t.Errorf("Ingest(%d) error %v, want ok %v", c.t, err, c.ok)
+		}
+	}
+
+	// The series stays in order.
+	s := in.Monitor.Series()
+	for i := 1; i < s.Len(); i++ {
+		if s[i].UnixTime <= s[i-1].UnixTime {
+			t.Errorf("series out of order at %d: %d after %d", i, s[i].UnixTime-now, s[i-1].UnixTime-now)
+		}
+	}
+	if s.Len() != 4 {
+		t.Errorf("series has %d measurements, want 4", s.Len())
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/cassava/pillr/guitar"
+)
+
+// Instrument ties together everything that is needed to monitor a single
+// instrument: the measurements, the warning LED and the notifications.
+type Instrument struct {
+	Name    string
+	Profile string
+	Sensor  Sensor
+
+	// Remote is whether the measurements are not read from a sensor,
+	// but only received from remote sensors; Nodes are the ids of
+	// the remote sensors that measure the instrument.
+	Remote bool
+	Nodes  []string
+
+	Levels   guitar.Levels
+	Filter   *Filter
+	Monitor  *Monitor
+	Warning  *WarningLED
+	Notifier *Notifier
+
+	updating sync.Mutex
+
+	mu       sync.RWMutex
+	danger   guitar.Danger
+	lastGood time.Time
+	failed   bool
+}
+
+func NewInstrument(ic InstrumentConfiguration) (*Instrument, error) {
+	g, err := guitar.Profile(ic.Profile)
+	if err != nil {
+		return nil, err
+	}
+	g = g.Localize(Conf.Locale)
+	sensor, err := NewInstrumentSensor(ic)
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewPersister(ic.Format, ic.DatabasePath())
+	if err != nil {
+		return nil, err
+	}
+	est, err := NewEstimator(Conf.Estimator)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	m, err := NewMonitor(p, est)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	f := NewFilter(Conf.Filter, Conf.Interval)
+	if s := m.Series(); s.Len() != 0 {
+		f.Seed(s.Top())
+	}
+
+	return &Instrument{
+		Name:     ic.Name,
+		Profile:  ic.Profile,
+		Sensor:   sensor,
+		Remote:   ic.Remote(),
+		Nodes:    ic.Nodes,
+		Levels:   g,
+		Filter:   f,
+		Monitor:  m,
+		Warning:  &WarningLED{LED: newLED(ic.PinWarningLED), Threat: guitar.Low},
+		Notifier: &Notifier{Levels: g, Monitor: m},
+		lastGood: time.Now(),
+	}, nil
+}
+
+// Danger returns the danger that the instrument was last found to be in.
+func (in *Instrument) Danger() guitar.Danger {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return in.danger
+}
+
+// Update records the measurement x and warns of any danger it implies.
+// Implausible measurements are rejected and only logged.
+func (in *Instrument) Update(x Measurement) {
+	err := in.update(x)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"instrument": in.Name,
+			"rejected":   in.Filter.Rejected(),
+		}).Warnf("rejecting %s: %s", x, err)
+	}
+}
+
+// Ingest records the measurement x received from a remote sensor, like
+// Update. If x is rejected, an error describing why is returned.
+func (in *Instrument) Ingest(x Measurement) error {
+	return in.update(x)
+}
+
+// update records x, which must be newer than the last measurement of the
+// instrument, as the series is kept in order. Measurements from remote
+// sensors that lag behind the others are therefore rejected.
+func (in *Instrument) update(x Measurement) error {
+	in.updating.Lock()
+	defer in.updating.Unlock()
+
+	if s := in.Monitor.Series(); s.Len() != 0 && x.UnixTime <= s.Top().UnixTime {
+		return fmt.Errorf("measurement is not newer than the last one at %s",
+			time.Unix(s.Top().UnixTime, 0).Format(measurementTimeFormat))
+	}
+	x, err := in.Filter.Apply(x)
+	if err != nil {
+		return err
+	}
+
+	in.Monitor.Update(x)
+	d := in.Levels.Assess(x.Temperature, x.Humidity)
+	s := in.Monitor.Series()
+	if rd := Conf.Rate.Threat(s); rd > d {
+		d = rd
+	}
+
+	in.mu.Lock()
+	in.danger = d
+	in.lastGood = time.Now()
+	if in.failed {
+		in.failed = false
+		log.WithField("instrument", in.Name).Infof("sensor %s recovered", in.Sensor)
+	}
+	in.Warning.Update(d)
+	in.mu.Unlock()
+
+	in.Notifier.Update(d)
+	log.WithFields(log.Fields{
+		"instrument": in.Name,
+		"danger":     d.String(),
+		"rate":       Conf.Rate.Rate(s),
+	}).Info(x)
+	return nil
+}
+
+// Stale returns whether the sensor has failed, and how long ago the last
+// valid measurement arrived.
+func (in *Instrument) Stale() (failed bool, age time.Duration) {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return in.failed, time.Since(in.lastGood)
+}
+
+// CheckStale raises a sensor failure if no valid measurement has arrived
+// within the stale window before now. The failure is cleared by the next
+// valid measurement.
+func (in *Instrument) CheckStale(now time.Time) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	age := now.Sub(in.lastGood)
+	if in.failed || Conf.Stale <= 0 || age <= Conf.Stale {
+		return
+	}
+	in.failed = true
+	log.WithField("instrument", in.Name).Errorf("no valid measurement from sensor %s for %s", in.Sensor, age)
+	in.Warning.Fail()
+	in.Notifier.Fail(in.Sensor, age)
+}
+
+// WatchStale checks for a sensor failure regularly until ctx is done.
+func (in *Instrument) WatchStale(ctx context.Context) {
+	if Conf.Stale <= 0 {
+		return
+	}
+	d := Conf.Stale / 10
+	if d < time.Second {
+		d = time.Second
+	}
+	t := time.NewTicker(d)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			in.CheckStale(now)
+		}
+	}
+}
+
+// Status describes the current state of an instrument. DangerName is the
+// name of the danger in the configured locale, and Age is the number of
+// seconds since the last valid measurement.
+type Status struct {
+	Instrument string         `json:"instrument"`
+	Profile    string         `json:"profile"`
+	Danger     guitar.Danger  `json:"danger"`
+	DangerName string         `json:"danger_name"`
+	Direction  string         `json:"direction"`
+	Rate       float32        `json:"rate"`
+	Rejected   int            `json:"rejected"`
+	Failed     bool           `json:"sensor_failure"`
+	Age        float64        `json:"age"`
+	Sensors    []SensorHealth `json:"sensors,omitempty"`
+	Advice     []string       `json:"advice"`
+	Latest     *Measurement   `json:"latest"`
+}
+
+// Status returns the current state of the instrument, including the
+// recommended actions for its danger and trend.
+func (in *Instrument) Status() Status {
+	st := Status{
+		Instrument: in.Name,
+		Profile:    in.Profile,
+		Danger:     in.Danger(),
+		Direction:  guitar.Steady.String(),
+		Rejected:   in.Filter.Rejected(),
+	}
+	st.DangerName = in.Levels.DangerName(st.Danger)
+	failed, age := in.Stale()
+	st.Failed, st.Age = failed, age.Seconds()
+	st.Sensors = in.sensorHealth()
+
+	s := in.Monitor.Series()
+	if s.Len() != 0 {
+		x := s.Top()
+		dir := Direction(in.Levels, s)
+		st.Direction = dir.String()
+		st.Rate = Conf.Rate.Rate(s)
+		st.Advice = in.Levels.Recommend(in.Levels.Metric.Value(x.Temperature, x.Humidity), dir)
+		st.Latest = &x
+	}
+	return st
+}
+
+// Health describes the health of the sensors of an instrument. Age is the
+// number of seconds since the last valid measurement, and Rejected the
+// number of implausible measurements.
+type Health struct {
+	Instrument string         `json:"instrument"`
+	Failed     bool           `json:"sensor_failure"`
+	Age        float64        `json:"age"`
+	Rejected   int            `json:"rejected"`
+	Sensors    []SensorHealth `json:"sensors"`
+}
+
+// Health returns the health of the sensors of the instrument.
+func (in *Instrument) Health() Health {
+	failed, age := in.Stale()
+	return Health{
+		Instrument: in.Name,
+		Failed:     failed,
+		Age:        age.Seconds(),
+		Rejected:   in.Filter.Rejected(),
+		Sensors:    in.sensorHealth(),
+	}
+}
+
+func (in *Instrument) sensorHealth() []SensorHealth {
+	if hr, ok := in.Sensor.(HealthReporter); ok {
+		return hr.Health()
+	}
+	return nil
+}
+
+func (in *Instrument) Close() {
+	in.Notifier.Close()
+	in.Monitor.Close()
+	in.Warning.LED.Stop()
+}
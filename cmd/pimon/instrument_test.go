@@ -0,0 +1,100 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cassava/pillr/guitar"
+	"github.com/cassava/pillr/led"
+)
+
+// testInstrument returns an instrument whose last valid measurement
+// arrived at lastGood, with its warning LED on pin.
+func testInstrument(t *testing.T, pin *led.FakePin, lastGood time.Time) *Instrument {
+	p, err := NewPersister("memory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewMonitor(p, &emaEstimator{lag: 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Instrument{
+		Name:     "test",
+		Sensor:   &FakeSensor{},
+		Levels:   guitar.Larrivee,
+		Filter:   NewFilter(Conf.Filter, time.Minute),
+		Monitor:  m,
+		Warning:  &WarningLED{LED: led.NewWithPin(pin), Threat: guitar.Low},
+		Notifier: &Notifier{Name: "test", Levels: guitar.Larrivee, Monitor: m},
+		lastGood: lastGood,
+	}
+}
+
+func TestCheckStale(t *testing.T) {
+	defer func(c Configuration) { *Conf = c }(*Conf)
+	s := newFakeSMTP(t, nil, false)
+	Conf.Mail = testMailConfiguration(s.Addr())
+	Conf.Stale = 5 * time.Minute
+	Conf.Patterns.Failure = []time.Duration{time.Hour, time.Hour}
+
+	start := time.Now()
+	pin := &led.FakePin{}
+	in := testInstrument(t, pin, start)
+	defer in.Close()
+
+	// Within the stale window nothing happens.
+	in.CheckStale(start.Add(5 * time.Minute))
+	if failed, _ := in.Stale(); failed || pin.Toggles() != 0 {
+		t.Fatal("sensor failure raised within the stale window")
+	}
+
+	// After it, the failure is raised once, with a notification.
+	in.CheckStale(start.Add(6 * time.Minute))
+	if failed, _ := in.Stale(); !failed {
+		t.Fatal("no sensor failure raised after the stale window")
+	}
+	waitFor(t, "failure pattern", pin.On)
+	in.Notifier.Close()
+	s.Wait(t)
+	if !strings.Contains(s.data, "[test] Sensor failure") || !strings.Contains(s.data, "for 6m0s") {
+		t.Errorf("failure notification:\n%s", s.data)
+	}
+
+	// It is not raised again, and the fake server would fail if it were.
+	Conf.Mail = MailConfiguration{}
+	n := pin.Toggles()
+	in.CheckStale(start.Add(time.Hour))
+	if pin.Toggles() != n {
+		t.Error("failure raised again while the sensor is still failed")
+	}
+
+	// A valid measurement clears the failure.
+	in.Update(Measurement{UnixTime: start.Unix(), Temperature: 21, Humidity: 48})
+	if failed, age := in.Stale(); failed || age > time.Second {
+		t.Errorf("Stale() after a valid measurement = %v, %s", failed, age)
+	}
+	if pin.On() {
+		t.Error("failure pattern still shown after a valid measurement")
+	}
+}
+
+func TestCheckStaleDisabled(t *testing.T) {
+	defer func(c Configuration) { *Conf = c }(*Conf)
+	Conf.Mail = MailConfiguration{}
+	Conf.Stale = 0
+
+	start := time.Now()
+	pin := &led.FakePin{}
+	in := testInstrument(t, pin, start)
+	defer in.Close()
+	in.CheckStale(start.Add(24 * time.Hour))
+	if failed, _ := in.Stale(); failed || pin.Toggles() != 0 {
+		t.Error("sensor failure raised although the stale check is disabled")
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/cassava/pillr/guitar"
+)
+
+type MailConfiguration struct {
+	// Server is the address of the SMTP server in the form host:port.
+	Server string `toml:"server"`
+
+	// StartTLS defines whether the connection should be upgraded with
+	// STARTTLS before authenticating and sending.
+	StartTLS bool `toml:"starttls"`
+
+	// TLS defines whether the connection uses TLS from the start,
+	// as is usual on port 465. It excludes StartTLS.
+	TLS bool `toml:"tls"`
+
+	// Username and Password are used for authentication with the server.
+	// If Username is empty, no authentication is performed.
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+
+	From string   `toml:"from"`
+	To   []string `toml:"to"`
+
+	// MinDanger is the danger that must at least be reached before an
+	// email is sent, such as "elevated".
+	MinDanger guitar.Danger `toml:"min_danger"`
+
+	// Timeout is how long connecting to the server and sending an email
+	// may take in total; if it is zero, defaultMailTimeout is used.
+	Timeout time.Duration `toml:"timeout"`
+
+	// tlsConfig replaces the default TLS configuration if it is set,
+	// such as to trust the certificate of a local server.
+	tlsConfig *tls.Config
+}
+
+// defaultMailTimeout is how long sending an email may take if no timeout
+// is given, so that an unresponsive server cannot hold up pimon for ever.
+const defaultMailTimeout = time.Minute
+
+// Enabled returns true when notification emails should be sent.
+func (mc MailConfiguration) Enabled() bool {
+	return mc.Server != "" && len(mc.To) != 0
+}
+
+// Validate returns an error if the configuration cannot work, such as when
+// a password would be sent in plain text, which net/smtp refuses to do for
+// any server other than localhost.
+func (mc MailConfiguration) Validate() error {
+	host, _, err := net.SplitHostPort(mc.Server)
+	if err != nil {
+		return fmt.Errorf("mail server: %v", err)
+	}
+	if mc.Timeout < 0 {
+		return errors.New("mail server: invalid timeout")
+	}
+	if mc.StartTLS && mc.TLS {
+		return errors.New("mail server: starttls and tls exclude each other")
+	}
+	if mc.Username != "" && !mc.StartTLS && !mc.TLS && !isLocalhost(host) {
+		return errors.New("mail server: authentication requires starttls or tls")
+	}
+	return nil
+}
+
+func isLocalhost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (mc MailConfiguration) clientTLS(host string) *tls.Config {
+	if mc.tlsConfig != nil {
+		return mc.tlsConfig
+	}
+	return &tls.Config{ServerName: host}
+}
+
+// SendMail sends a plain text email with the given subject and body to all
+// recipients in mc.
+func SendMail(mc MailConfiguration, subject, body string) error {
+	if !mc.Enabled() {
+		return errors.New("mail server or recipients unspecified")
+	}
+
+	host, _, err := net.SplitHostPort(mc.Server)
+	if err != nil {
+		return err
+	}
+
+	timeout := mc.Timeout
+	if timeout <= 0 {
+		timeout = defaultMailTimeout
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	if mc.TLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", mc.Server, mc.clientTLS(host))
+	} else {
+		conn, err = dialer.Dial("tcp", mc.Server)
+	}
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if mc.StartTLS {
+		err = c.StartTLS(mc.clientTLS(host))
+		if err != nil {
+			return err
+		}
+	}
+	if mc.Username != "" {
+		err = c.Auth(smtp.PlainAuth("", mc.Username, mc.Password, host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.Mail(mc.From)
+	if err != nil {
+		return err
+	}
+	for _, addr := range mc.To {
+		err = c.Rcpt(addr)
+		if err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	msg, err := composeMail(mc, subject, body)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func composeMail(mc MailConfiguration, subject, body string) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", mc.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(mc.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buf.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(&buf)
+	_, err := w.Write([]byte(body))
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
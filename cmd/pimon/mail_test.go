@@ -0,0 +1,271 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io/ioutil"
+	"mime/quotedprintable"
+	"net"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP is a local SMTP server that accepts a single message.
+type fakeSMTP struct {
+	ln       net.Listener
+	cert     *tls.Config
+	implicit bool
+	done     chan struct{}
+
+	// The following are recorded from the session.
+	tls  bool
+	auth string
+	from string
+	to   []string
+	data string
+	err  error
+}
+
+// newFakeSMTP starts a server that offers STARTTLS if cert is not nil,
+// or that uses TLS from the start if implicit is true as well.
+func newFakeSMTP(t *testing.T, cert *tls.Config, implicit bool) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTP{ln: ln, cert: cert, implicit: implicit, done: make(chan struct{})}
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTP) Addr() string { return s.ln.Addr().String() }
+
+// Wait waits until the session is over and closes the server.
+func (s *fakeSMTP) Wait(t *testing.T) {
+	<-s.done
+	s.ln.Close()
+	if s.err != nil {
+		t.Fatal("fake server: ", s.err)
+	}
+}
+
+func (s *fakeSMTP) serve() {
+	defer close(s.done)
+	conn, err := s.ln.Accept()
+	if err != nil {
+		s.err = err
+		return
+	}
+	defer func() { conn.Close() }()
+	if s.implicit {
+		conn, s.tls = tls.Server(conn, s.cert), true
+	}
+
+	tc := textproto.NewConn(conn)
+	tc.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			s.err = err
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		arg := strings.TrimSpace(line[len(verb):])
+		switch verb {
+		case "EHLO":
+			if s.cert != nil && !s.tls {
+				tc.PrintfLine("250-fake")
+				tc.PrintfLine("250-STARTTLS")
+			} else {
+				tc.PrintfLine("250-fake")
+			}
+			tc.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tc.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, s.cert)
+			if err := tlsConn.Handshake(); err != nil {
+				s.err = err
+				return
+			}
+			conn, s.tls = tlsConn, true
+			tc = textproto.NewConn(conn)
+		case "AUTH":
+			fs := strings.Fields(arg)
+			bs, _ := base64.StdEncoding.DecodeString(fs[len(fs)-1])
+			s.auth = string(bs)
+			tc.PrintfLine("235 authenticated")
+		case "MAIL":
+			s.from = arg
+			tc.PrintfLine("250 ok")
+		case "RCPT":
+			s.to = append(s.to, arg)
+			tc.PrintfLine("250 ok")
+		case "DATA":
+			tc.PrintfLine("354 go ahead")
+			bs, err := ioutil.ReadAll(tc.DotReader())
+			if err != nil {
+				s.err = err
+				return
+			}
+			s.data = string(bs)
+			tc.PrintfLine("250 queued")
+		case "QUIT":
+			tc.PrintfLine("221 bye")
+			return
+		default:
+			tc.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// testCertificate returns a server configuration and a client configuration
+// that trusts it, for 127.0.0.1.
+func testCertificate() (server, client *tls.Config) {
+	srv := httptest.NewUnstartedServer(nil)
+	srv.StartTLS()
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	return &tls.Config{Certificates: srv.TLS.Certificates},
+		&tls.Config{ServerName: "127.0.0.1", RootCAs: pool}
+}
+
+func checkMail(t *testing.T, s *fakeSMTP) {
+	if s.from != "FROM:<pimon@example.com>" {
+		t.Errorf("sender = %q", s.from)
+	}
+	if len(s.to) != 2 || s.to[1] != "TO:<bob@example.com>" {
+		t.Errorf("recipients = %q", s.to)
+	}
+	if s.auth != "\x00pimon\x00secret" {
+		t.Errorf("authentication = %q", s.auth)
+	}
+
+	// The dot reader has already turned CRLF into LF.
+	i := strings.Index(s.data, "\n\n")
+	if i < 0 {
+		t.Fatalf("message without body: %q", s.data)
+	}
+	header, body := s.data[:i], s.data[i+2:]
+	if !strings.Contains(header, "Subject: =?utf-8?q?Gitarre_in_Gefahr=E2=80=94HIGH?=") {
+		t.Errorf("subject not encoded in header:\n%s", header)
+	}
+	bs, err := ioutil.ReadAll(quotedprintable.NewReader(strings.NewReader(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Luftfeuchtigkeit: 85%\n"; string(bs) != want {
+		t.Errorf("body = %q, want %q", bs, want)
+	}
+}
+
+func testMailConfiguration(server string) MailConfiguration {
+	return MailConfiguration{
+		Server:   server,
+		Username: "pimon",
+		Password: "secret",
+		From:     "pimon@example.com",
+		To:       []string{"alice@example.com", "bob@example.com"},
+	}
+}
+
+func TestSendMailPlain(t *testing.T) {
+	s := newFakeSMTP(t, nil, false)
+	mc := testMailConfiguration(s.Addr())
+	if err := mc.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := SendMail(mc, "Gitarre in Gefahr—HIGH", "Luftfeuchtigkeit: 85%\n"); err != nil {
+		t.Fatal(err)
+	}
+	s.Wait(t)
+	if s.tls {
+		t.Error("connection upgraded without starttls")
+	}
+	checkMail(t, s)
+}
+
+func TestSendMailStartTLS(t *testing.T) {
+	server, client := testCertificate()
+	s := newFakeSMTP(t, server, false)
+	mc := testMailConfiguration(s.Addr())
+	mc.StartTLS = true
+	mc.tlsConfig = client
+	if err := SendMail(mc, "Gitarre in Gefahr—HIGH", "Luftfeuchtigkeit: 85%\n"); err != nil {
+		t.Fatal(err)
+	}
+	s.Wait(t)
+	if !s.tls {
+		t.Error("connection not upgraded with starttls")
+	}
+	checkMail(t, s)
+}
+
+func TestSendMailTLS(t *testing.T) {
+	server, client := testCertificate()
+	s := newFakeSMTP(t, server, true)
+	mc := testMailConfiguration(s.Addr())
+	mc.TLS = true
+	mc.tlsConfig = client
+	if err := SendMail(mc, "Gitarre in Gefahr—HIGH", "Luftfeuchtigkeit: 85%\n"); err != nil {
+		t.Fatal(err)
+	}
+	s.Wait(t)
+	checkMail(t, s)
+}
+
+func TestSendMailTimeout(t *testing.T) {
+	// The server accepts the connection, but never greets.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	mc := testMailConfiguration(ln.Addr().String())
+	mc.Timeout = 50 * time.Millisecond
+	done := make(chan error)
+	go func() { done <- SendMail(mc, "subject", "body") }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("mail sent to a server that does not answer")
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("SendMail does not time out")
+	}
+}
+
+func TestMailValidate(t *testing.T) {
+	mc := testMailConfiguration("mail.example.com:587")
+	if mc.Validate() == nil {
+		t.Error("authentication without starttls or tls accepted")
+	}
+	mc.StartTLS = true
+	if err := mc.Validate(); err != nil {
+		t.Error(err)
+	}
+	mc.TLS = true
+	if mc.Validate() == nil {
+		t.Error("both starttls and tls accepted")
+	}
+	mc.StartTLS = false
+	if err := mc.Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -5,10 +5,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"text/template"
 	"time"
 
@@ -25,7 +28,25 @@ import (
 var Conf = &Configuration{
 	Listen:   ":8080",
 	Conserve: false,
+	Format:   "csv",
 	Interval: 10 * time.Second,
+	Stale:    5 * time.Minute,
+	Sensor:   "dht22",
+	Fusion:   "median",
+	I2CBus:   1,
+	LED:      "gpio",
+
+	ReadTimeout: time.Minute,
+	ReplaySpeed: 1,
+
+	Simulation: defaultSimulation,
+	Filter:     defaultFilter,
+	Estimator:  defaultEstimator,
+	Retention:  defaultRetention,
+
+	Rate: RateConfiguration{
+		Window: time.Hour,
+	},
 
 	Patterns: PatternConfiguration{
 		Low:      []time.Duration{0},
@@ -34,6 +55,7 @@ var Conf = &Configuration{
 		High:     led.High,
 		Severe:   led.Severe,
 		Extreme:  led.Extreme,
+		Failure:  led.Failure,
 	},
 }
 
@@ -44,6 +66,9 @@ const (
 	configSuffix   = "pimon/conf.toml"
 	databaseSuffix = "pimon/measurements.dat"
 	lockSuffix     = "pimon/lock.pid"
+
+	// defaultProfile is the profile of instruments if none is configured.
+	defaultProfile = "larrivee"
 )
 
 type Configuration struct {
@@ -52,16 +77,216 @@ type Configuration struct {
 	Listen string `toml:"listen"`
 
 	// Conserve defines if we only store entries that differ from previous entries.
+	// This thins out the series where the climate is steady, which makes the
+	// rate of change computed over it less accurate.
 	Conserve bool `toml:"conserve"`
 
+	// Database is the file the measurements are stored in, if only a single
+	// instrument is monitored. If left empty, XDG_DATA_HOME/pimon is used.
+	Database string `toml:"database"`
+
+	// Format is the format the measurements are stored in, either "csv"
+	// or "gob", or "memory" to not store them at all. It is also used for
+	// instruments that do not specify one. pimon refuses to open a database
+	// that already holds measurements in the other format.
+	Format string `toml:"format"`
+
 	// Interval defines the minimum time between measurements.
 	Interval time.Duration `toml:"interval"`
 
+	// Jitter is the maximum random delay added to each read, and
+	// ReadTimeout how long to wait for a read before giving up on it.
+	Jitter      time.Duration `toml:"jitter"`
+	ReadTimeout time.Duration `toml:"read_timeout"`
+
+	// Stale defines how long to wait for a valid measurement before
+	// raising a sensor failure. If it is zero, no failure is raised.
+	Stale time.Duration `toml:"stale"`
+
+	// Profile is the name of the guitar profile, such as "larrivee", which
+	// is listed by the profiles command. It is also used for instruments
+	// that do not specify one. If left empty, "larrivee" is used.
+	Profile string `toml:"profile"`
+
+	// Locale defines the language of notifications, such as "de".
+	// If there is no translation for the locale, English is used.
+	Locale string `toml:"locale"`
+
 	PinWarningLED   int `toml:"pin_warning_led"`
 	PinHeartbeatLED int `toml:"pin_heartbeat_led"`
 	PinSensor       int `toml:"pin_sensor"`
 
+	// Sensor is the driver of the sensor, such as "dht22" or "dht11".
+	// It is also used for instruments that do not specify a sensor.
+	Sensor string `toml:"sensor"`
+
+	// Sensors lists several sensors at the same location, whose readings
+	// are fused. If it is empty, the single sensor given above is used.
+	Sensors []SensorConfiguration `toml:"sensors"`
+
+	// Fusion is how the readings of several sensors are fused, either by
+	// "median" or by weighted "mean". It is also used for instruments
+	// that do not specify it.
+	Fusion string `toml:"fusion"`
+
+	// Calibration contains the calibrations of sensors by their name,
+	// such as "DHT22@4" or "SHT31@1:0x44". The calibrate command writes
+	// these to a separate configuration file.
+	Calibration map[string]Calibration `toml:"calibration"`
+
+	// I2CBus and I2CAddress define where an I2C sensor is attached.
+	I2CBus     int `toml:"i2c_bus"`
+	I2CAddress int `toml:"i2c_address"`
+
+	// Simulation describes the climate that the "simulated" sensor produces.
+	// It is also used for instruments that do not specify a simulation.
+	Simulation SimulationConfiguration `toml:"simulation"`
+
+	// LED is the backend that the LEDs are driven with, either "gpio"
+	// or "fake", which lets pimon run without any GPIO hardware.
+	LED string `toml:"led"`
+
+	// Replay is the file of measurements that the "replay" sensor reads,
+	// and ReplaySpeed how many times faster than real time it does so.
+	// Both are also used for instruments that do not specify them.
+	Replay      string  `toml:"replay"`
+	ReplaySpeed float64 `toml:"replay_speed"`
+
+	// Command is the command that the "exec" sensor runs for each reading,
+	// such as ["ble-hygrometer", "--json"]. It is also used for instruments
+	// that do not specify one.
+	Command []string `toml:"command"`
+
+	// Instruments lists all instruments that should be monitored.
+	// If it is empty, a single instrument is monitored with the
+	// pins and profile given above.
+	Instruments []InstrumentConfiguration `toml:"instrument"`
+
+	// Filter defines which readings are plausible enough to be used.
+	Filter FilterConfiguration `toml:"filter"`
+
+	// Estimator defines how the belief of the true climate is formed.
+	Estimator EstimatorConfiguration `toml:"estimator"`
+
+	// Retention defines how long measurements are kept and when they
+	// are rolled up into aggregates; by default, all are kept as they are.
+	Retention RetentionConfiguration `toml:"retention"`
+
+	// Rate defines the danger of the humidity changing too quickly.
+	Rate RateConfiguration `toml:"rate"`
+
 	Patterns PatternConfiguration `toml:"patterns"`
+
+	// Ingest configures the endpoint that remote sensors post to.
+	Ingest IngestConfiguration `toml:"ingest"`
+
+	// Mail configures the notification emails that are sent when
+	// the danger rises. If no recipients are given, no emails are sent.
+	Mail MailConfiguration `toml:"mail"`
+}
+
+type InstrumentConfiguration struct {
+	// Name identifies the instrument in the log and the web API.
+	Name string `toml:"name"`
+
+	// Profile is the name of the guitar profile, such as "larrivee".
+	Profile string `toml:"profile"`
+
+	// Database is the file the measurements are stored in, in Format.
+	// If left empty, XDG_DATA_HOME/pimon/<name>.dat is used.
+	Database string `toml:"database"`
+	Format   string `toml:"format"`
+
+	// Sensor is the driver of the sensor, such as "dht22".
+	Sensor string `toml:"sensor"`
+
+	// Sensors lists several sensors for the instrument, whose readings are
+	// fused by Fusion. Sensors inherit what they leave unspecified from
+	// the instrument.
+	Sensors []SensorConfiguration `toml:"sensors"`
+	Fusion  string                `toml:"fusion"`
+
+	PinWarningLED int `toml:"pin_warning_led"`
+	PinSensor     int `toml:"pin_sensor"`
+
+	// I2CBus and I2CAddress define where an I2C sensor, such as the
+	// "bme280" or "sht31", is attached.
+	I2CBus     int `toml:"i2c_bus"`
+	I2CAddress int `toml:"i2c_address"`
+
+	// Simulation describes the climate for the "simulated" sensor.
+	Simulation *SimulationConfiguration `toml:"simulation"`
+
+	// Replay is the file of measurements that the "replay" sensor reads,
+	// and ReplaySpeed how many times faster than real time it does so.
+	Replay      string  `toml:"replay"`
+	ReplaySpeed float64 `toml:"replay_speed"`
+
+	// Command is the command that the "exec" sensor runs for each reading.
+	Command []string `toml:"command"`
+
+	// Nodes are the ids of the remote sensors that post measurements of
+	// the instrument to /ingest. Measurements can also be posted with the
+	// name of the instrument as id. If the sensor is "remote", only posted
+	// measurements are used.
+	Nodes []string `toml:"nodes"`
+}
+
+// Remote returns whether the instrument is only measured by remote sensors.
+func (ic InstrumentConfiguration) Remote() bool {
+	return strings.ToLower(ic.Sensor) == "remote" && len(ic.Sensors) == 0
+}
+
+func (ic InstrumentConfiguration) SensorConfig() SensorConfiguration {
+	return SensorConfiguration{
+		Driver:      ic.Sensor,
+		Pin:         ic.PinSensor,
+		I2CBus:      ic.I2CBus,
+		I2CAddress:  ic.I2CAddress,
+		Simulation:  ic.Simulation,
+		Replay:      ic.Replay,
+		ReplaySpeed: ic.ReplaySpeed,
+		Command:     ic.Command,
+	}
+}
+
+// SensorConfigs returns the configurations of all sensors of the instrument.
+func (ic InstrumentConfiguration) SensorConfigs() []SensorConfiguration {
+	base := ic.SensorConfig()
+	if len(ic.Sensors) == 0 {
+		return []SensorConfiguration{base}
+	}
+
+	scs := make([]SensorConfiguration, len(ic.Sensors))
+	for i, sc := range ic.Sensors {
+		if sc.Driver == "" {
+			sc.Driver = base.Driver
+		}
+		if sc.I2CBus == 0 {
+			sc.I2CBus = base.I2CBus
+		}
+		if sc.Simulation == nil {
+			sc.Simulation = base.Simulation
+		}
+		if sc.Replay == "" {
+			sc.Replay = base.Replay
+		}
+		if sc.ReplaySpeed == 0 {
+			sc.ReplaySpeed = base.ReplaySpeed
+		}
+		if len(sc.Command) == 0 {
+			sc.Command = base.Command
+		}
+		scs[i] = sc
+	}
+	return scs
+}
+
+func (ic InstrumentConfiguration) DatabasePath() string {
+	if ic.Database != "" {
+		return ic.Database
+	}
+	return xdg.UserData("pimon/" + ic.Name + ".dat")
 }
 
 type PatternConfiguration struct {
@@ -71,6 +296,9 @@ type PatternConfiguration struct {
 	High     []time.Duration `toml:"high"`
 	Severe   []time.Duration `toml:"severe"`
 	Extreme  []time.Duration `toml:"extreme"`
+
+	// Failure is shown when the sensor has failed.
+	Failure []time.Duration `toml:"failure"`
 }
 
 func (pc PatternConfiguration) Get(d guitar.Danger) []time.Duration {
@@ -93,15 +321,201 @@ func (pc PatternConfiguration) Get(d guitar.Danger) []time.Duration {
 	}
 }
 
+// InstrumentList returns the configured instruments, or a single default
+// instrument if none are configured.
+func (c Configuration) InstrumentList() []InstrumentConfiguration {
+	profile := c.Profile
+	if profile == "" {
+		profile = defaultProfile
+	}
+	if len(c.Instruments) == 0 {
+		db := c.Database
+		if db == "" {
+			db = xdg.UserData(databaseSuffix)
+		}
+		return []InstrumentConfiguration{{
+			Name:          "guitar",
+			Profile:       profile,
+			Database:      db,
+			Format:        c.Format,
+			Sensor:        c.Sensor,
+			Sensors:       c.Sensors,
+			Fusion:        c.Fusion,
+			PinWarningLED: c.PinWarningLED,
+			PinSensor:     c.PinSensor,
+			I2CBus:        c.I2CBus,
+			I2CAddress:    c.I2CAddress,
+			Simulation:    &c.Simulation,
+			Replay:        c.Replay,
+			ReplaySpeed:   c.ReplaySpeed,
+			Command:       c.Command,
+		}}
+	}
+
+	is := make([]InstrumentConfiguration, len(c.Instruments))
+	for i, ic := range c.Instruments {
+		if ic.Profile == "" {
+			ic.Profile = profile
+		}
+		if ic.Sensor == "" {
+			ic.Sensor = c.Sensor
+		}
+		if ic.Fusion == "" {
+			ic.Fusion = c.Fusion
+		}
+		if ic.Format == "" {
+			ic.Format = c.Format
+		}
+		if ic.I2CBus == 0 {
+			ic.I2CBus = c.I2CBus
+		}
+		if ic.Simulation == nil {
+			ic.Simulation = &c.Simulation
+		}
+		if ic.Replay == "" {
+			ic.Replay = c.Replay
+		}
+		if ic.ReplaySpeed == 0 {
+			ic.ReplaySpeed = c.ReplaySpeed
+		}
+		if len(ic.Command) == 0 {
+			ic.Command = c.Command
+		}
+		is[i] = ic
+	}
+	return is
+}
+
+// Instrument returns the configuration of the named instrument.
+// If name is empty, the first instrument is returned.
+func (c Configuration) Instrument(name string) (InstrumentConfiguration, error) {
+	is := c.InstrumentList()
+	if name == "" {
+		return is[0], nil
+	}
+	for _, ic := range is {
+		if ic.Name == name {
+			return ic, nil
+		}
+	}
+	return InstrumentConfiguration{}, fmt.Errorf("unknown instrument %s", name)
+}
+
+// Simulate changes the configuration so that all sensors are simulated
+// and all LEDs are fake.
+func (c *Configuration) Simulate() {
+	c.Sensor = "simulated"
+	c.LED = "fake"
+	setDrivers(c.Sensors, "simulated")
+	for i := range c.Instruments {
+		c.Instruments[i].Sensor = "simulated"
+		setDrivers(c.Instruments[i].Sensors, "simulated")
+	}
+}
+
+// ReplayFrom changes the configuration so that all sensors replay
+// the measurements in file.
+func (c *Configuration) ReplayFrom(file string) {
+	c.Sensor = "replay"
+	c.Replay = file
+	setDrivers(c.Sensors, "replay")
+	for i := range c.Instruments {
+		c.Instruments[i].Sensor = "replay"
+		c.Instruments[i].Replay = file
+		setDrivers(c.Instruments[i].Sensors, "replay")
+	}
+}
+
+// KeepInMemory changes the configuration so that measurements are only kept
+// in memory, so that trial runs do not mix with the real measurements.
+func (c *Configuration) KeepInMemory() {
+	c.Format = "memory"
+	for i := range c.Instruments {
+		c.Instruments[i].Format = "memory"
+	}
+}
+
+func validFormat(format string) bool {
+	for _, f := range PersisterFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func setDrivers(scs []SensorConfiguration, driver string) {
+	for i := range scs {
+		scs[i].Driver = driver
+		scs[i].Replay = ""
+	}
+}
+
 func (c Configuration) Assert() {
-	if c.PinWarningLED <= 0 {
-		log.Fatal("warning LED pin unspecified")
+	if c.Database != "" && len(c.Instruments) != 0 {
+		log.Fatal("database can only be given for a single instrument, give it for each instrument instead")
+	}
+	names := make(map[string]bool)
+	for _, ic := range c.InstrumentList() {
+		if ic.Name == "" {
+			log.Fatal("instrument name unspecified")
+		}
+		if names[ic.Name] {
+			log.Fatalf("instrument %s specified more than once", ic.Name)
+		}
+		names[ic.Name] = true
+		if _, err := guitar.Profile(ic.Profile); err != nil {
+			log.Fatalf("instrument %s: %s", ic.Name, err)
+		}
+		if c.LED == "gpio" && ic.PinWarningLED <= 0 {
+			log.Fatalf("instrument %s: warning LED pin unspecified", ic.Name)
+		}
+		for _, sc := range ic.SensorConfigs() {
+			if err := sc.Validate(); err != nil {
+				log.Fatalf("instrument %s: %s", ic.Name, err)
+			}
+			if len(ic.Sensors) != 0 && strings.ToLower(sc.Driver) == "remote" {
+				log.Fatalf("instrument %s: remote sensors cannot be fused", ic.Name)
+			}
+		}
+		if !validFormat(ic.Format) {
+			log.Fatalf("instrument %s: unknown database format %q, expecting csv, gob or memory", ic.Name, ic.Format)
+		}
+		if ic.Remote() && c.Ingest.Token == "" {
+			log.Fatalf("instrument %s: remote sensor requires an ingest token", ic.Name)
+		}
+		if ic.Fusion != "median" && ic.Fusion != "mean" {
+			log.Fatalf("instrument %s: unknown fusion %q, expecting median or mean", ic.Name, ic.Fusion)
+		}
+	}
+	if c.LED != "gpio" && c.LED != "fake" {
+		log.Fatalf("unknown LED backend %q, expecting gpio or fake", c.LED)
+	}
+	if _, err := NewEstimator(c.Estimator); err != nil {
+		log.Fatal(err)
+	}
+	if err := c.Retention.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if c.Mail.Enabled() {
+		if err := c.Mail.Validate(); err != nil {
+			log.Fatal(err)
+		}
 	}
-	if c.PinSensor <= 0 {
-		log.Fatal("sensor pin unspecified")
+	if c.Interval <= 0 {
+		log.Fatal("measurement interval must be positive")
 	}
-	if c.Interval < 0 {
-		log.Fatal("measurment interval is invalid")
+	if c.Jitter < 0 || c.Jitter > c.Interval {
+		log.Fatal("measurement jitter is invalid")
+	}
+	if c.ReadTimeout < 0 {
+		log.Fatal("read timeout is invalid")
+	}
+	if c.Stale < 0 {
+		log.Fatal("stale measurement window is invalid")
+	}
+	if c.Rate.Window < 0 {
+		log.Fatal("rate of change window is invalid")
 	}
 }
 
@@ -110,73 +524,138 @@ func (c Configuration) Assert() {
 // Main (pimon) command {{{
 
 var (
-	database string
 	conf     string
+	database string
+	simulate bool
+	replay   string
 )
 
 var pimonCmd = &cobra.Command{
 	Use:   "pimon",
 	Short: "monitor temperature and humidity",
 	Long: `Pimon monitors the temperature and humidity and warns you
-if if is not in the safe range defined by Larrivee.
+if if is not in the safe range defined by Larrivee, or by one of the
+other profiles listed by the profiles command, as given with --profile.
 
   If pimon is run with default options and without any specific command,
   it will read all the configuration files it finds in the XDG config path.
-  It will also store any measurements in XDG_DATA_HOME/pimon/th.csv.
+  It will also store any measurements in XDG_DATA_HOME/pimon/measurements.dat,
+  unless another file is given with --database. When simulating or replaying,
+  measurements are only kept in memory, unless --database is given.
+
+  Several instruments can be monitored at once by listing them in the
+  configuration file, each with its own sensor, LED, profile and database:
+
+    [[instrument]]
+    name = "dreadnought"
+    profile = "larrivee"
+    pin_sensor = 4
+    pin_warning_led = 17
 `,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if database != "" {
+			Conf.Database = database
+		}
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if simulate {
+			Conf.Simulate()
+		}
+		if replay != "" {
+			Conf.ReplayFrom(replay)
+		}
+		if database == "" && (simulate || replay != "") {
+			Conf.KeepInMemory()
+		}
 		Conf.Assert()
 
 		exitIf(pimonLock())
 		defer pimonUnlock()
 
-		exitIf(embd.InitGPIO())
-		defer embd.CloseGPIO()
+		if Conf.LED == "gpio" {
+			exitIf(embd.InitGPIO())
+			defer embd.CloseGPIO()
+		}
+		defer closeI2C()
 
-		done := make(chan struct{})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
 		c := make(chan os.Signal, 1)
 		signal.Notify(c, os.Interrupt)
 
-		nl := &WarningLED{led.New(Conf.PinWarningLED), guitar.Low}
-		csv, err := NewCSVPersister(xdg.UserData(databaseSuffix))
-		if err != nil {
-			log.Error("error persisting: ", err)
-			return
+		var is []*Instrument
+		defer func() {
+			for _, in := range is {
+				in.Close()
+			}
+		}()
+		for _, ic := range Conf.InstrumentList() {
+			in, err := NewInstrument(ic)
+			if err != nil {
+				log.Errorf("error setting up instrument %s: %s", ic.Name, err)
+				return
+			}
+			is = append(is, in)
 		}
-		m, err := NewMonitor(csv, 0.1)
-		if err != nil {
-			log.Error("error reading persistent file: ", err)
-			return
+		if len(is) > 1 {
+			for _, in := range is {
+				in.Notifier.Name = in.Name
+			}
 		}
-		g := guitar.Larrivee
 
-		go Serve(Conf.Listen, m)
-		go WatchSensor(Conf.PinSensor, done, func(x Measurement) {
-			m.Update(x)
-			d := g.Threat(x.Humidity)
-			nl.Update(d)
-			log.WithFields(log.Fields{
-				"danger": d.String(),
-			}).Info(x)
-		})
+		go Serve(Conf.Listen, is)
+		var wg sync.WaitGroup
+		for _, in := range is {
+			// Once a sensor has no more readings, as when a replay has
+			// finished, its instrument is no longer checked for staleness.
+			stale := ctx
+			if !in.Remote {
+				var finished context.CancelFunc
+				stale, finished = context.WithCancel(ctx)
+				wg.Add(1)
+				go func(in *Instrument) {
+					defer wg.Done()
+					defer finished()
+					WatchSensor(ctx, in.Sensor, in.Update)
+				}(in)
+			}
+			wg.Add(1)
+			go func(in *Instrument, stale context.Context) {
+				defer wg.Done()
+				in.WatchStale(stale)
+			}(in, stale)
+		}
 
 		<-c
-		close(done)
-		m.Close()
-		nl.LED.Stop()
+		cancel()
+		wg.Wait()
 	},
 }
 
 func pimonInit() {
 	pf := pimonCmd.PersistentFlags()
 	pf.StringVar(&database, "database", "", "read from and store measurements in this file")
+	pf.StringVar(&Conf.Format, "format", Conf.Format, "format to store measurements in, csv, gob or memory")
 	pf.StringVar(&Conf.Listen, "listen", Conf.Listen, "enable online access at this port")
 	pf.BoolVarP(&Conf.Conserve, "conserve", "c", Conf.Conserve, "only store differing entries")
 	pf.DurationVarP(&Conf.Interval, "interval", "i", Conf.Interval, "minimum time between measurements")
+	pf.BoolVar(&simulate, "simulate", false, "simulate sensors and LEDs, keeping measurements in memory unless --database is given")
+	pf.StringVar(&replay, "replay", "", "replay measurements from this file, keeping them in memory unless --database is given")
+	pf.Float64Var(&Conf.ReplaySpeed, "replay-speed", Conf.ReplaySpeed, "how many times faster than real time to replay")
+	pf.StringVar(&Conf.Profile, "profile", Conf.Profile, "profile of instruments that do not specify one, such as larrivee")
+	pf.StringVar(&Conf.Locale, "locale", Conf.Locale, "language of notifications")
 	pf.IntVarP(&Conf.PinWarningLED, "pin-warning", "W", Conf.PinWarningLED, "pin number for warning LED")
 	pf.IntVarP(&Conf.PinHeartbeatLED, "pin-heartbeat", "H", Conf.PinHeartbeatLED, "pin number for system LED")
 	pf.IntVarP(&Conf.PinSensor, "pin-sensor", "S", Conf.PinSensor, "pin number for sensor")
-
+	pf.StringVar(&Conf.Sensor, "sensor", Conf.Sensor, "sensor driver, such as dht22, bme280 or sht31")
+
+	chartInit()
+	calibrateInit()
+	statusInit()
+	pimonCmd.AddCommand(chartCmd)
+	pimonCmd.AddCommand(calibrateCmd)
+	pimonCmd.AddCommand(statusCmd)
+	pimonCmd.AddCommand(profilesCmd)
 	pimonCmd.AddCommand(versionCmd)
 }
 
@@ -191,9 +670,12 @@ func mergeConf() {
 	cp := os.Getenv(configEnv)
 	if cp != "" {
 		merge(cp)
-		return
+	} else {
+		xdg.MergeConfigR(configSuffix, func(file string) error { merge(file); return nil })
+	}
+	if file := xdg.FindConfig(calibrationSuffix); file != "" {
+		merge(file)
 	}
-	xdg.MergeConfigR(configSuffix, func(file string) error { merge(file); return nil })
 }
 
 func pimonLock() error {
@@ -222,6 +704,23 @@ func main() {
 
 // }}}
 
+// Profiles command {{{
+
+var profilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "list available profiles",
+	Long:  "List the profiles that instruments and other items can be monitored with.",
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, name := range guitar.ProfileNames() {
+			g := guitar.Profiles[name]
+			low, high := g.Safe()
+			fmt.Printf("%-14s %v–%v%s\t%s\n", name, low, high, g.Metric.Unit(), g.Description)
+		}
+	},
+}
+
+// }}}
+
 // Version command {{{
 
 var versionCmd = &cobra.Command{
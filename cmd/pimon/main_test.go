@@ -0,0 +1,30 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestInstrumentListProfile(t *testing.T) {
+	var c Configuration
+	if is := c.InstrumentList(); len(is) != 1 || is[0].Profile != defaultProfile {
+		t.Errorf("profile without configuration is %q, want %q", is[0].Profile, defaultProfile)
+	}
+
+	c.Profile = "violin"
+	if is := c.InstrumentList(); is[0].Profile != "violin" {
+		t.Errorf("profile of the single instrument is %q, want violin", is[0].Profile)
+	}
+
+	c.Instruments = []InstrumentConfiguration{{Name: "a"}, {Name: "b", Profile: "piano"}}
+	is := c.InstrumentList()
+	if is[0].Profile != "violin" || is[1].Profile != "piano" {
+		t.Errorf("profiles of the instruments are %q and %q, want violin and piano", is[0].Profile, is[1].Profile)
+	}
+
+	c.Profile = ""
+	if is := c.InstrumentList(); is[0].Profile != defaultProfile {
+		t.Errorf("profile of an instrument without one is %q, want %q", is[0].Profile, defaultProfile)
+	}
+}
@@ -10,14 +10,35 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/cassava/pillr/guitar"
 )
 
 type Measurement struct {
 	UnixTime    int64
 	Temperature float32
 	Humidity    float32
+	// Pressure is the air pressure in hPa, or zero if the sensor
+	// does not measure it.
+	Pressure float32
+
+	// Span is the number of seconds from UnixTime that an aggregate of Count
+	// measurements covers, or zero if this is a single measurement. The
+	// temperature, humidity and pressure of an aggregate are the means,
+	// and the extremes are kept as well. PressureCount is the number of
+	// measurements that had a pressure; older aggregates, where it is zero
+	// despite a pressure, count all of them.
+	Span           int64
+	Count          int
+	MinTemperature float32
+	MaxTemperature float32
+	MinHumidity    float32
+	MaxHumidity    float32
+	PressureCount  int
 }
 
 // Measurement implemenation {{{
@@ -28,74 +49,164 @@ func (x *Measurement) Update(lag float32, m Measurement) {
 	x.UnixTime = m.UnixTime
 	x.Temperature = (1-lag)*x.Temperature + lag*m.Temperature
 	x.Humidity = (1-lag)*x.Humidity + lag*m.Humidity
+	x.Pressure = (1-lag)*x.Pressure + lag*m.Pressure
 }
 
 func (x Measurement) String() string {
 	t := time.Unix(x.UnixTime, 0).Format(time.Stamp)
-	return fmt.Sprintf("%v: %.1f C at %.1f%% humidity", t, x.Temperature, x.Humidity)
+	var agg string
+	if x.Span != 0 {
+		agg = fmt.Sprintf(" (mean of %d over %s)", x.Count, time.Duration(x.Span)*time.Second)
+	}
+	if x.Pressure != 0 {
+		return fmt.Sprintf("%v: %.1f C at %.1f%% humidity and %.1f hPa%s", t, x.Temperature, x.Humidity, x.Pressure, agg)
+	}
+	return fmt.Sprintf("%v: %.1f C at %.1f%% humidity%s", t, x.Temperature, x.Humidity, agg)
 }
 
+// MarshalRecord returns the time, temperature and humidity of x,
+// followed by the pressure if x has one. If x is an aggregate, the
+// pressure is followed by the span, the count, the extremes and the
+// number of measurements that had a pressure.
 func (x Measurement) MarshalRecord() []string {
-	return []string{time.Unix(x.UnixTime, 0).Format(measurementTimeFormat),
-		strconv.FormatFloat(float64(x.Temperature), 'f', 1, 32),
-		strconv.FormatFloat(float64(x.Humidity), 'f', 1, 32),
+	r := []string{time.Unix(x.UnixTime, 0).Format(measurementTimeFormat),
+		formatFloat(x.Temperature),
+		formatFloat(x.Humidity),
+	}
+	if x.Pressure != 0 || x.Span != 0 {
+		var p string
+		if x.Pressure != 0 {
+			p = formatFloat(x.Pressure)
+		}
+		r = append(r, p)
+	}
+	if x.Span != 0 {
+		r = append(r,
+			strconv.FormatInt(x.Span, 10),
+			strconv.Itoa(x.Count),
+			formatFloat(x.MinTemperature),
+			formatFloat(x.MaxTemperature),
+			formatFloat(x.MinHumidity),
+			formatFloat(x.MaxHumidity),
+		)
+		n := x.PressureCount
+		if n == 0 && x.Pressure != 0 {
+			n = x.Count
+		}
+		r = append(r, strconv.Itoa(n))
 	}
+	return r
+}
+
+func formatFloat(f float32) string {
+	return strconv.FormatFloat(float64(f), 'f', 1, 32)
 }
 
 func (x Measurement) MarshalCSV() ([]byte, error) {
-	var buf bytes.Buffer
-	buf.WriteString("time,temperature,humidity\n")
-	r := x.MarshalRecord()
-	buf.WriteString(r[0])
-	buf.WriteRune(',')
-	buf.WriteString(r[1])
-	buf.WriteRune(',')
-	buf.WriteString(r[2])
-	buf.WriteRune('\n')
-	return buf.Bytes(), nil
+	return Series{x}.MarshalCSV()
 }
 
 func (x Measurement) MarshalJSON() ([]byte, error) {
 	r := x.MarshalRecord()
-	return []byte(fmt.Sprintf(`{"time": "%s", "temperature": %s, "humidity": %s}`, r[0], r[1], r[2])), nil
+	var pressure, agg string
+	if x.Pressure != 0 {
+		pressure = `, "pressure": ` + r[3]
+	}
+	if x.Span != 0 {
+		agg = fmt.Sprintf(`, "span": %s, "count": %s, "min_temperature": %s, "max_temperature": %s, `+
+			`"min_humidity": %s, "max_humidity": %s, "pressure_count": %s`, r[4], r[5], r[6], r[7], r[8], r[9], r[10])
+	}
+	return []byte(fmt.Sprintf(`{"time": "%s", "temperature": %s, "humidity": %s%s, `+
+		`"dew_point": %s, "absolute_humidity": %s, "emc": %s%s}`,
+		r[0], r[1], r[2], pressure, jsonFloat(x.DewPoint()), jsonFloat(x.AbsoluteHumidity()), jsonFloat(x.EMC()), agg)), nil
+}
+
+// jsonFloat formats f with one decimal, or as null if it is not finite,
+// such as the dew point of completely dry air.
+func jsonFloat(f float32) string {
+	if math.IsInf(float64(f), 0) || math.IsNaN(float64(f)) {
+		return "null"
+	}
+	return strconv.FormatFloat(float64(f), 'f', 1, 32)
 }
 
 func (m *Measurement) UnmarshalRecord(rs []string) error {
-	if len(rs) != 3 {
+	if len(rs) != 3 && len(rs) != 4 && len(rs) != 10 && len(rs) != 11 {
 		return errors.New("invalid record length")
 	}
 
-	t, err := time.Parse(measurementTimeFormat, rs[0])
+	// The time is written in local time, so it must be read as such.
+	t, err := time.ParseInLocation(measurementTimeFormat, rs[0], time.Local)
 	if err != nil {
 		return err
 	}
-	m.UnixTime = t.Unix()
-	f, err := strconv.ParseFloat(rs[1], 32)
+	*m = Measurement{UnixTime: t.Unix()}
+	err = parseFloats(rs[1:3], &m.Temperature, &m.Humidity)
 	if err != nil {
 		return err
 	}
-	m.Temperature = float32(f)
-	f, err = strconv.ParseFloat(rs[2], 32)
-	if err != nil {
-		return err
+	if len(rs) > 3 && rs[3] != "" {
+		err = parseFloats(rs[3:4], &m.Pressure)
+		if err != nil {
+			return err
+		}
+	}
+	if len(rs) >= 10 && rs[4] != "" {
+		m.Span, err = strconv.ParseInt(rs[4], 10, 64)
+		if err != nil {
+			return err
+		}
+		m.Count, err = strconv.Atoi(rs[5])
+		if err != nil {
+			return err
+		}
+		err = parseFloats(rs[6:10], &m.MinTemperature, &m.MaxTemperature, &m.MinHumidity, &m.MaxHumidity)
+		if err != nil {
+			return err
+		}
+		if m.Pressure != 0 {
+			m.PressureCount = m.Count
+		}
+		if len(rs) == 11 {
+			m.PressureCount, err = strconv.Atoi(rs[10])
+		}
+	}
+	return err
+}
+
+func parseFloats(rs []string, fs ...*float32) error {
+	for i, r := range rs {
+		f, err := strconv.ParseFloat(r, 32)
+		if err != nil {
+			return err
+		}
+		*fs[i] = float32(f)
 	}
-	m.Humidity = float32(f)
 	return nil
 }
 
+// DewPoint returns the dew point in °C.
+func (x Measurement) DewPoint() float32 { return guitar.DewPoint(x.Temperature, x.Humidity) }
+
+// AbsoluteHumidity returns the water vapour density in g/m³.
+func (x Measurement) AbsoluteHumidity() float32 {
+	return guitar.AbsoluteHumidity(x.Temperature, x.Humidity)
+}
+
+// EMC returns the equilibrium moisture content of wood in %.
+func (x Measurement) EMC() float32 { return guitar.EMC(x.Temperature, x.Humidity) }
+
 // Same returns true when the measurement itself is the same.
 func (x Measurement) Same(y Measurement) bool {
-	return x.Temperature == y.Temperature && x.Humidity == y.Humidity
+	return x.Temperature == y.Temperature && x.Humidity == y.Humidity && x.Pressure == y.Pressure
 }
 
 // }}}
 
 // One days worth of measurements should take up about 1,382,400 bytes.
 // This means we should have no problem storing a week of measurements.
-// After that, we simplify the measurements, so we have once every minute.
-// And after that, we can simplify further, averaging the hours. Eventually,
-// we'll resort to using a database instead of this here. Probably should
-// do that from the start.
+// After that, a retention policy can roll the measurements up into
+// aggregates, such as once every minute and later once every hour.
 type Series []Measurement
 
 // Series implementation {{{
@@ -104,25 +215,69 @@ func (s *Series) Add(m Measurement) { *s = append(*s, m) }
 func (s Series) Len() int           { return len(s) }
 func (s Series) Top() Measurement   { return s[len(s)-1] }
 
+// Rate returns the rate of change per hour of value over the measurements
+// in the window before the most recent one, computed by linear regression.
+// If there are not enough measurements, zero is returned.
+func (s Series) Rate(window time.Duration, value func(Measurement) float32) float32 {
+	if s.Len() < 2 {
+		return 0
+	}
+
+	top := s.Top().UnixTime
+	start := top - int64(window/time.Second)
+	var n, sx, sy, sxx, sxy float64
+	for i := s.Len() - 1; i >= 0 && s[i].UnixTime >= start; i-- {
+		x := float64(s[i].UnixTime-top) / 3600
+		y := float64(value(s[i]))
+		n++
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+	d := n*sxx - sx*sx
+	if n < 2 || d == 0 {
+		return 0
+	}
+	return float32((n*sxy - sx*sy) / d)
+}
+
+// MarshalCSV writes the series with a header; the pressure column is only
+// included if at least one of the measurements has a pressure, and the
+// aggregate columns if at least one is an aggregate.
 func (s Series) MarshalCSV() ([]byte, error) {
+	header := []string{"time", "temperature", "humidity"}
+	for _, x := range s {
+		if x.Span != 0 {
+			header = []string{"time", "temperature", "humidity", "pressure", "span", "count",
+				"min_temperature", "max_temperature", "min_humidity", "max_humidity", "pressure_count"}
+			break
+		} else if x.Pressure != 0 {
+			header = []string{"time", "temperature", "humidity", "pressure"}
+		}
+	}
+
 	var buf bytes.Buffer
-	buf.WriteString("time,temperature,humidity\n")
+	buf.WriteString(strings.Join(header, ","))
+	buf.WriteRune('\n')
 	for _, x := range s {
 		r := x.MarshalRecord()
-		buf.WriteString(r[0])
-		buf.WriteRune(',')
-		buf.WriteString(r[1])
-		buf.WriteRune(',')
-		buf.WriteString(r[2])
+		for len(r) < len(header) {
+			r = append(r, "")
+		}
+		buf.WriteString(strings.Join(r, ","))
 		buf.WriteRune('\n')
 	}
 	return buf.Bytes(), nil
 }
 
+// UnmarshalCSV reads measurements as written by MarshalCSV or by the
+// CSV persister, so the header is optional.
 func (s *Series) UnmarshalCSV(bs []byte) error {
 	buf := bytes.NewBuffer(bs)
 	cr := csv.NewReader(buf)
-	for {
+	cr.FieldsPerRecord = -1 // the pressure and aggregates are optional
+	for first := true; ; first = false {
 		r, err := cr.Read()
 		if err != nil {
 			if err == io.EOF {
@@ -130,6 +285,9 @@ func (s *Series) UnmarshalCSV(bs []byte) error {
 			}
 			return err
 		}
+		if first && len(r) != 0 && r[0] == "time" {
+			continue
+		}
 
 		var x Measurement
 		err = x.UnmarshalRecord(r)
@@ -6,40 +6,64 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/csv"
 	"encoding/gob"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
 )
 
 type Monitor struct {
 	sync.RWMutex
 
-	belief Measurement
-	series Series
-	p      Persister
-	lag    float32
+	est      Estimator
+	series   Series
+	retained int64
+
+	// The measurements are written to p outside of the lock, so that
+	// rewriting a large database does not hold up readers. Measurements
+	// wait in pending until they are written, unless rewrite is set,
+	// in which case the whole series is written instead. Writes are
+	// serialized by persisting.
+	persisting sync.Mutex
+	p          Persister
+	pending    []Measurement
+	rewrite    bool
 }
 
 // Monitor implementation {{{
 
-func NewMonitor(p Persister, lag float32) (*Monitor, error) {
+// NewMonitor returns a monitor that stores measurements with p and forms
+// its belief with e, starting from the last stored measurement. If that is
+// too old, the estimator starts afresh with the next measurement instead.
+func NewMonitor(p Persister, e Estimator) (*Monitor, error) {
 	s, err := p.ReadAll()
 	if err != nil {
 		return nil, err
 	}
-	return &Monitor{
-		lag:    lag,
+	if s.Len() != 0 {
+		e.Update(s.Top())
+	}
+	m := &Monitor{
+		est:    e,
 		p:      p,
 		series: s,
-	}, nil
+	}
+	m.retain(time.Now())
+	m.persist()
+	return m, nil
 }
 
-func (m *Monitor) Belief() Measurement {
+func (m *Monitor) Belief() Belief {
 	m.RLock()
 	defer m.RUnlock()
-	return m.belief
+	return m.est.Belief()
 }
 
 func (m *Monitor) Series() Series {
@@ -50,23 +74,62 @@ func (m *Monitor) Series() Series {
 
 func (m *Monitor) Update(x Measurement) {
 	m.Lock()
-	defer m.Unlock()
-
-	m.belief.Update(m.lag, x)
+	m.est.Update(x)
 	if Conf.Conserve && m.series.Len() != 0 && m.series.Top().Same(x) {
+		m.Unlock()
 		return
 	}
 
 	m.series.Add(x)
-	if m.p != nil {
+	m.pending = append(m.pending, x)
+	if t := time.Unix(x.UnixTime, 0); t.Sub(time.Unix(m.retained, 0)) >= retentionEvery {
+		m.retain(t)
+	}
+	m.Unlock()
+	m.persist()
+}
+
+// retain applies the retention policy at the time now to the measurements
+// in memory, and marks the persister to be rewritten with them.
+func (m *Monitor) retain(now time.Time) {
+	m.retained = now.Unix()
+	s, changed := Conf.Retention.Apply(m.series, now)
+	if !changed {
+		return
+	}
+	m.series = s
+	m.pending, m.rewrite = nil, true
+}
+
+// persist writes the pending measurements to the persister, or rewrites
+// it with the series if retention has changed it.
+func (m *Monitor) persist() {
+	if m.p == nil {
+		return
+	}
+	m.persisting.Lock()
+	defer m.persisting.Unlock()
+
+	m.Lock()
+	xs, rewrite, s := m.pending, m.rewrite, m.series
+	m.pending, m.rewrite = nil, false
+	m.Unlock()
+
+	if rewrite {
+		if err := m.p.Rewrite(s); err != nil {
+			log.Error("error applying retention to database: ", err)
+		}
+		return
+	}
+	for _, x := range xs {
 		m.p.Persist(x)
 	}
 }
 
 func (m *Monitor) Close() {
 	if m.p != nil {
-		m.Lock()
-		defer m.Unlock()
+		m.persisting.Lock()
+		defer m.persisting.Unlock()
 		m.p.Close()
 	}
 }
@@ -76,9 +139,120 @@ func (m *Monitor) Close() {
 type Persister interface {
 	ReadAll() (Series, error)
 	Persist(m Measurement) error
+	// Rewrite replaces all stored measurements with s.
+	Rewrite(s Series) error
 	Close() error
 }
 
+// PersisterFormats are the formats that measurements can be stored in,
+// where "memory" does not store them at all.
+var PersisterFormats = []string{"csv", "gob", "memory"}
+
+// NewPersister returns a persister that stores measurements in the file
+// at path in the given format. If the file already holds measurements in
+// another format, an error is returned instead of mixing the two.
+func NewPersister(format, path string) (Persister, error) {
+	if format != "memory" {
+		if err := checkFormat(format, path); err != nil {
+			return nil, err
+		}
+	}
+	switch format {
+	case "csv":
+		return NewCSVPersister(path)
+	case "gob":
+		return NewGobPersister(path)
+	case "memory":
+		return memoryPersister{}, nil
+	default:
+		return nil, fmt.Errorf("unknown database format %q, expecting csv, gob or memory", format)
+	}
+}
+
+// ReadSeries reads the measurements stored in the file at path in the given
+// format, without opening it for writing, which would disturb a gob stream
+// that is still being written to.
+func ReadSeries(format, path string) (Series, error) {
+	if err := checkFormat(format, path); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch format {
+	case "csv":
+		bs, err := ioutil.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+		var s Series
+		err = s.UnmarshalCSV(bs)
+		return s, err
+	case "gob":
+		return readGob(f)
+	default:
+		return nil, fmt.Errorf("cannot read database format %q, expecting csv or gob", format)
+	}
+}
+
+// detectFormat returns the format of the measurements in the file at path,
+// either "csv" or "gob", or "" if the file is empty or does not exist.
+// A CSV file starts with a header or the time of its first measurement.
+func detectFormat(path string) (string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, len(measurementTimeFormat))
+	n, err := io.ReadFull(f, head)
+	if n == 0 {
+		if err == io.EOF {
+			err = nil
+		}
+		return "", err
+	}
+	head = head[:n]
+	if bytes.HasPrefix(head, []byte("time,")) {
+		return "csv", nil
+	}
+	if len(head) == len(measurementTimeFormat) {
+		if _, err := time.Parse(measurementTimeFormat, string(head)); err == nil {
+			return "csv", nil
+		}
+	}
+	return "gob", nil
+}
+
+// checkFormat returns an error if the file at path holds measurements
+// in another format than the given one.
+func checkFormat(format, path string) error {
+	actual, err := detectFormat(path)
+	if err != nil {
+		return err
+	}
+	if actual != "" && actual != format {
+		return fmt.Errorf("%s holds measurements in %s format, not %s; set the format to %s or use another database",
+			path, actual, format, actual)
+	}
+	return nil
+}
+
+// memoryPersister keeps the measurements only in the memory of the monitor,
+// such as when simulating, so that they do not mix with real measurements.
+type memoryPersister struct{}
+
+func (memoryPersister) ReadAll() (Series, error)    { return nil, nil }
+func (memoryPersister) Persist(m Measurement) error { return nil }
+func (memoryPersister) Rewrite(s Series) error      { return nil }
+func (memoryPersister) Close() error                { return nil }
+
 // Persister implementation {{{
 // CSV Persister {{{
 
@@ -113,6 +287,39 @@ func (p *csvPersister) ReadAll() (Series, error) {
 	return s, err
 }
 
+func (p *csvPersister) Rewrite(s Series) error {
+	path := p.file.Name()
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	for _, x := range s {
+		w.Write(x.MarshalRecord())
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// Measurements that are still buffered are in s already.
+	p.file.Close()
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	p.file, err = open(path)
+	if err != nil {
+		return err
+	}
+	p.w = csv.NewWriter(p.file)
+	return nil
+}
+
 func (p *csvPersister) Persist(m Measurement) error {
 	return p.w.Write(m.MarshalRecord())
 }
@@ -126,14 +333,85 @@ func (p *csvPersister) Close() error {
 
 // Gob Persister {{{
 
+// gobPersister stores measurements as a single gob stream. Since a stream
+// cannot be continued by another encoder, the file is rewritten when it is
+// opened, and then appended to by the same encoder.
 type gobPersister struct {
+	path string
 	file *os.File
 	buf  *bufio.Writer
 	enc  *gob.Encoder
 }
 
+func NewGobPersister(path string) (*gobPersister, error) {
+	var s Series
+	if f, err := os.Open(path); err == nil {
+		s, err = readGob(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %v", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	p := &gobPersister{path: path}
+	if err := p.Rewrite(s); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Rewrite writes s as a new stream, which is then appended to.
+func (p *gobPersister) Rewrite(s Series) error {
+	tmp := p.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	buf := bufio.NewWriter(file)
+	enc := gob.NewEncoder(buf)
+	for _, x := range s {
+		if err := enc.Encode(x); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := os.Rename(tmp, p.path); err != nil {
+		file.Close()
+		return err
+	}
+
+	if p.file != nil {
+		p.file.Close()
+	}
+	p.file, p.buf, p.enc = file, buf, enc
+	return nil
+}
+
+func (p *gobPersister) ReadAll() (Series, error) {
+	err := p.buf.Flush()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.file.Seek(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer p.file.Seek(0, 2)
+	return readGob(p.file)
+}
+
 func (p *gobPersister) Persist(m Measurement) error {
-	return p.enc.Encode(m.MarshalRecord())
+	err := p.enc.Encode(m)
+	if err != nil {
+		return err
+	}
+	return p.buf.Flush()
 }
 
 func (p *gobPersister) Close() error {
@@ -141,15 +419,24 @@ func (p *gobPersister) Close() error {
 	return p.file.Close()
 }
 
-func NewGobPersister(path string) (*gobPersister, error) {
-	file, err := open(path)
-	if err != nil {
-		return nil, err
+// readGob reads the measurements of a gob stream. A truncated measurement at
+// the end, such as when pimon was killed while writing it, is dropped.
+func readGob(r io.Reader) (Series, error) {
+	dec := gob.NewDecoder(bufio.NewReader(r))
+	var s Series
+	for {
+		var x Measurement
+		err := dec.Decode(&x)
+		if err == io.EOF {
+			return s, nil
+		} else if err == io.ErrUnexpectedEOF {
+			log.Warnf("dropping truncated measurement after %d measurements", s.Len())
+			return s, nil
+		} else if err != nil {
+			return s, err
+		}
+		s.Add(x)
 	}
-
-	buf := bufio.NewWriter(file)
-	enc := gob.NewEncoder(buf)
-	return &gobPersister{file, buf, enc}, nil
 }
 
 // }}}
@@ -0,0 +1,68 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPersisterFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pimon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// CSV stores local time, which must round-trip outside of UTC too.
+	if time.Local == time.UTC {
+		defer func(loc *time.Location) { time.Local = loc }(time.Local)
+		time.Local = time.FixedZone("CET", 3600)
+	}
+	x := Measurement{UnixTime: time.Date(2015, 11, 1, 12, 0, 0, 0, time.Local).Unix(), Temperature: 21, Humidity: 45}
+	for _, format := range []string{"csv", "gob"} {
+		path := filepath.Join(dir, "measurements."+format)
+		if f, err := detectFormat(path); f != "" || err != nil {
+			t.Errorf("detectFormat of a missing file = %q, %v", f, err)
+		}
+		p, err := NewPersister(format, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Persist(x)
+		p.Close()
+
+		if f, err := detectFormat(path); f != format || err != nil {
+			t.Errorf("detectFormat of %s = %q, %v", format, f, err)
+		}
+		other := map[string]string{"csv": "gob", "gob": "csv"}[format]
+		if _, err := NewPersister(other, path); err == nil {
+			t.Errorf("%s database opened as %s", format, other)
+		}
+		if _, err := ReadSeries(other, path); err == nil {
+			t.Errorf("%s database read as %s", format, other)
+		}
+		s, err := ReadSeries(format, path)
+		if err != nil || s.Len() != 1 {
+			t.Fatalf("ReadSeries(%s) = %v, %v", format, s, err)
+		}
+		if got, want := time.Unix(s.Top().UnixTime, 0), time.Unix(x.UnixTime, 0); !got.Equal(want) {
+			t.Errorf("%s measurement read back at %s, want %s", format, got, want)
+		}
+		if s.Top() != x {
+			t.Errorf("%s measurement read back as %+v, want %+v", format, s.Top(), x)
+		}
+	}
+
+	// A CSV file may also start with a header.
+	path := filepath.Join(dir, "header.csv")
+	ioutil.WriteFile(path, []byte("time,temperature,humidity\n"), 0666)
+	if f, _ := detectFormat(path); f != "csv" {
+		t.Errorf("detectFormat of a CSV header = %q", f)
+	}
+}
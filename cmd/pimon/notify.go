@@ -0,0 +1,128 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/cassava/pillr/guitar"
+)
+
+// Notifier sends a notification email whenever the danger rises.
+// If Name is set, it is prefixed to the subject.
+//
+// Emails are sent in the background, so that a slow mail server does not
+// hold up the measurements; Close waits for those still being sent.
+type Notifier struct {
+	Name    string
+	Levels  guitar.Levels
+	Monitor *Monitor
+	Threat  guitar.Danger
+
+	sending sync.WaitGroup
+}
+
+func (n *Notifier) Update(d guitar.Danger) {
+	rising := d > n.Threat
+	n.Threat = d
+	if !rising || d < Conf.Mail.MinDanger || !Conf.Mail.Enabled() {
+		return
+	}
+
+	subject, body, err := RenderNotification(n.Levels, n.Monitor.Series(), d)
+	if err != nil {
+		log.Error("error rendering notification: ", err)
+		return
+	}
+	n.send(subject, body, log.Fields{
+		"instrument": n.Name,
+		"danger":     d.String(),
+	})
+}
+
+// Fail sends a notification that no valid measurement has been read from
+// the sensor s for the duration age.
+func (n *Notifier) Fail(s Sensor, age time.Duration) {
+	if !Conf.Mail.Enabled() {
+		return
+	}
+
+	subject := "Sensor failure: no measurements"
+	if n.Name != "" {
+		subject = fmt.Sprintf("[%s] %s", n.Name, subject)
+	}
+	body := fmt.Sprintf("No valid measurement has been read from the sensor %s for %s.\n", s, age.Round(time.Second))
+	if x := n.Monitor.Series(); x.Len() != 0 {
+		body += fmt.Sprintf("The last measurement was %s.\n", x.Top())
+	}
+	body += "\nUntil the sensor works again, the danger to the instrument is unknown.\n"
+	n.send(subject, body, log.Fields{
+		"instrument": n.Name,
+		"failure":    true,
+	})
+}
+
+// send sends the notification in the background, logging the outcome
+// with the given fields.
+func (n *Notifier) send(subject, body string, fields log.Fields) {
+	n.sending.Add(1)
+	go func() {
+		defer n.sending.Done()
+		err := SendMail(Conf.Mail, subject, body)
+		if err != nil {
+			log.WithFields(fields).Error("error sending notification: ", err)
+			return
+		}
+		log.WithFields(fields).Info("notification sent to ", Conf.Mail.To)
+	}()
+}
+
+// Close waits until all notifications have been sent.
+func (n *Notifier) Close() {
+	n.sending.Wait()
+}
+
+const (
+	// trendHours is the number of hours shown in the trend chart
+	// of a notification.
+	trendHours = 48
+
+	// trendWindow is the time over which the direction of the trend
+	// is determined, and trendTolerance the rate of change per hour
+	// below which the trend is considered steady.
+	trendWindow    = time.Hour
+	trendTolerance = 0.5
+)
+
+// Direction returns the direction in which the metric of g is moving in s.
+func Direction(g guitar.Levels, s Series) guitar.Direction {
+	rate := s.Rate(trendWindow, func(x Measurement) float32 {
+		return g.Metric.Value(x.Temperature, x.Humidity)
+	})
+	return guitar.Heading(rate, trendTolerance)
+}
+
+// RenderNotification renders the notification of g for the most recent
+// measurement in s, returning the subject and the body. If the danger d is
+// higher than what the measurement implies, such as when the humidity is
+// changing quickly, d is reported instead.
+func RenderNotification(g guitar.Levels, s Series, d guitar.Danger) (subject, body string, err error) {
+	if s.Len() == 0 {
+		return "", "", errors.New("no measurement data")
+	}
+
+	x := s.Top()
+	low, high := g.SafeHumidity(x.Temperature)
+	trend := s.Chart(time.Unix(x.UnixTime, 0), trendHours, low, high)
+	r := g.Report(g.Metric.Value(x.Temperature, x.Humidity), Direction(g, s), trend)
+	if d > r.Risk {
+		r.Risk, r.RiskName = d, g.DangerName(d)
+	}
+	return g.Details.Render(r)
+}
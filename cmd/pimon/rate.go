@@ -0,0 +1,64 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/cassava/pillr/guitar"
+)
+
+// RateConfiguration defines the danger of fast humidity swings, which can
+// crack wood even when the humidity itself is in the safe range.
+//
+// Each threshold is the rate of change in %RH per hour, in either direction,
+// from which the respective danger applies. A threshold of zero is ignored,
+// and all of them are zero by default, so that the rate of change is only
+// reported. Sensible thresholds for a guitar are 4, 6, 10, 15 and 25.
+//
+// The rate of change is the slope of a regression over the measurements in
+// the window. If Conserve is set, repeated measurements are not stored, so
+// a steady climate is underrepresented and a change following it has more
+// weight than it should; the thresholds should then be set more generously.
+type RateConfiguration struct {
+	// Window is the time over which the rate of change is computed.
+	// If it is zero, the rate of change is not analysed.
+	Window time.Duration `toml:"window"`
+
+	Moderate float32 `toml:"moderate"`
+	Elevated float32 `toml:"elevated"`
+	High     float32 `toml:"high"`
+	Severe   float32 `toml:"severe"`
+	Extreme  float32 `toml:"extreme"`
+}
+
+// Rate returns the rate of change of the humidity in s in %RH per hour.
+func (rc RateConfiguration) Rate(s Series) float32 {
+	if rc.Window <= 0 {
+		return 0
+	}
+	return s.Rate(rc.Window, func(x Measurement) float32 { return x.Humidity })
+}
+
+// Threat returns the danger that the rate of change in s implies.
+func (rc RateConfiguration) Threat(s Series) guitar.Danger {
+	r := float32(math.Abs(float64(rc.Rate(s))))
+	for _, t := range []struct {
+		limit  float32
+		danger guitar.Danger
+	}{
+		{rc.Extreme, guitar.Extreme},
+		{rc.Severe, guitar.Severe},
+		{rc.High, guitar.High},
+		{rc.Elevated, guitar.Elevated},
+		{rc.Moderate, guitar.Moderate},
+	} {
+		if t.limit > 0 && r >= t.limit {
+			return t.danger
+		}
+	}
+	return guitar.Low
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cassava/pillr/guitar"
+)
+
+// ramp returns measurements every ten minutes over the given hours, whose
+// humidity changes by slope per hour and ends at 50%.
+func ramp(hours int, slope float32) Series {
+	var s Series
+	end := time.Date(2015, 11, 1, 12, 0, 0, 0, time.UTC)
+	for i := -hours * 6; i <= 0; i++ {
+		s.Add(Measurement{
+			UnixTime:    end.Add(time.Duration(i) * 10 * time.Minute).Unix(),
+			Temperature: 21,
+			Humidity:    50 + slope*float32(i)/6,
+		})
+	}
+	return s
+}
+
+func TestRate(t *testing.T) {
+	rc := RateConfiguration{Window: 2 * time.Hour}
+	for _, slope := range []float32{0, 3, -7.5, 20} {
+		if r := rc.Rate(ramp(6, slope)); math.Abs(float64(r-slope)) > 1e-3 {
+			t.Errorf("Rate of a ramp of %.1f%%/h is %.3f%%/h", slope, r)
+		}
+	}
+
+	// Only the measurements in the window count.
+	s := ramp(4, -10)
+	for i := range s[:len(s)-13] {
+		s[i].Humidity = 90
+	}
+	if r := rc.Rate(s); math.Abs(float64(r+10)) > 1e-3 {
+		t.Errorf("Rate ignoring the measurements before the window is %.3f%%/h, want -10", r)
+	}
+
+	for _, s := range []Series{nil, ramp(0, 10), ramp(6, 10)[:1]} {
+		if r := rc.Rate(s); r != 0 {
+			t.Errorf("Rate of %d measurements is %.3f, want 0", s.Len(), r)
+		}
+	}
+	if r := (RateConfiguration{}).Rate(ramp(6, 10)); r != 0 {
+		t.Errorf("Rate without a window is %.3f, want 0", r)
+	}
+}
+
+func TestThreat(t *testing.T) {
+	rc := RateConfiguration{Window: time.Hour, Moderate: 4, Elevated: 6, High: 10, Severe: 15, Extreme: 25}
+	for _, c := range []struct {
+		slope float32
+		want  guitar.Danger
+	}{
+		{0, guitar.Low},
+		{3.9, guitar.Low},
+		{-3.9, guitar.Low},
+		{4.1, guitar.Moderate},
+		{-4.1, guitar.Moderate},
+		{8, guitar.Elevated},
+		{-12, guitar.High},
+		{15.1, guitar.Severe},
+		{-30, guitar.Extreme},
+	} {
+		if d := rc.Threat(ramp(3, c.slope)); d != c.want {
+			t.Errorf("Threat of %.1f%%/h is %v, want %v", c.slope, d, c.want)
+		}
+	}
+
+	// Thresholds of zero are skipped.
+	rc = RateConfiguration{Window: time.Hour, High: 10}
+	for _, c := range []struct {
+		slope float32
+		want  guitar.Danger
+	}{
+		{9, guitar.Low},
+		{-11, guitar.High},
+		{50, guitar.High},
+	} {
+		if d := rc.Threat(ramp(3, c.slope)); d != c.want {
+			t.Errorf("Threat of %.1f%%/h with only a high threshold is %v, want %v", c.slope, d, c.want)
+		}
+	}
+
+	if d := (RateConfiguration{Window: time.Hour}).Threat(ramp(3, 50)); d != guitar.Low {
+		t.Errorf("Threat without thresholds is %v, want low", d)
+	}
+	if d := rc.Threat(ramp(0, 0)); d != guitar.Low {
+		t.Errorf("Threat of a single measurement is %v, want low", d)
+	}
+}
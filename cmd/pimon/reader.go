@@ -0,0 +1,147 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// SensorReader reads a sensor at a fixed cadence. Only one read is ever in
+// progress: if a read takes longer than the timeout, it is abandoned and
+// further reads are skipped until it returns.
+type SensorReader struct {
+	Sensor Sensor
+
+	// Interval is the time between reads; if it is not positive,
+	// minInterval is used instead.
+	Interval time.Duration
+
+	// Jitter is the maximum random delay added to each read, so that
+	// several sensors are not all read at the same time.
+	Jitter time.Duration
+
+	// Timeout is how long to wait for a read; if it is zero,
+	// reads are waited for indefinitely.
+	Timeout time.Duration
+
+	Clock Clock
+}
+
+// minInterval is the interval that is used if none is given, so that
+// the reader does not read the sensor in a busy loop.
+const minInterval = time.Second
+
+type readResult struct {
+	Reading
+	err error
+}
+
+// NewSensorReader returns a reader of s with the cadence and timeout
+// from the configuration, where clock provides the time.
+func NewSensorReader(s Sensor, clock Clock) *SensorReader {
+	return &SensorReader{
+		Sensor:   s,
+		Interval: Conf.Interval,
+		Jitter:   Conf.Jitter,
+		Timeout:  Conf.ReadTimeout,
+		Clock:    clock,
+	}
+}
+
+// WatchSensor reads s and passes each measurement to f, until ctx is done.
+func WatchSensor(ctx context.Context, s Sensor, f func(Measurement)) {
+	NewSensorReader(s, RealClock).Run(ctx, f)
+}
+
+// Run reads the sensor once every interval and passes each measurement to f,
+// until ctx is done or the sensor has no more readings, as a replay that has
+// finished. Reads that fail otherwise are logged and skipped.
+func (sr *SensorReader) Run(ctx context.Context, f func(Measurement)) {
+	interval := sr.Interval
+	if interval <= 0 {
+		interval = minInterval
+	}
+	rnd := rand.New(rand.NewSource(sr.Clock.Now().UnixNano()))
+	next := sr.Clock.Now()
+	var pending chan readResult
+	for {
+		wait := next.Sub(sr.Clock.Now())
+		if sr.Jitter > 0 {
+			wait += time.Duration(rnd.Int63n(int64(sr.Jitter)))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-sr.Clock.After(wait):
+		}
+
+		// Ticks that were missed, such as during a slow read, are skipped
+		// instead of being caught up on.
+		now := sr.Clock.Now()
+		for !next.After(now) {
+			next = next.Add(interval)
+		}
+
+		if pending != nil {
+			select {
+			case <-pending:
+				pending = nil
+			default:
+				log.Warnf("%s: previous read still in progress, skipping read", sr.Sensor)
+				continue
+			}
+		}
+
+		ch := make(chan readResult, 1)
+		go func() {
+			r, err := sr.Sensor.Read()
+			ch <- readResult{r, err}
+		}()
+
+		var timeout <-chan time.Time
+		if sr.Timeout > 0 {
+			timeout = sr.Clock.After(sr.Timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeout:
+			log.Errorf("%s: read timed out after %s", sr.Sensor, sr.Timeout)
+			pending = ch
+		case r := <-ch:
+			if r.err == ErrReplayFinished {
+				log.Infof("%s: %s", sr.Sensor, r.err)
+				return
+			}
+			if x, ok := sr.measurement(r); ok {
+				f(x)
+			}
+		}
+	}
+}
+
+func (sr *SensorReader) measurement(r readResult) (Measurement, bool) {
+	s := sr.Sensor
+	if r.err != nil {
+		log.WithFields(log.Fields{"retries": r.Retries}).Errorf("%s: %s", s, r.err)
+		return Measurement{}, false
+	}
+	log.WithFields(log.Fields{"retries": r.Retries}).Debugf("%s: temp=%v humidity=%v", s, r.Temperature, r.Humidity)
+
+	t := r.Time
+	if t.IsZero() {
+		t = sr.Clock.Now()
+	}
+	return Measurement{
+		UnixTime:    t.Unix(),
+		Temperature: r.Temperature,
+		Humidity:    r.Humidity,
+		Pressure:    r.Pressure,
+	}, true
+}
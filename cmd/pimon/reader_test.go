@@ -0,0 +1,192 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var readerStart = time.Date(2015, 11, 1, 12, 0, 0, 0, time.UTC)
+
+// runReader runs sr until ctx is done, returning the channel that the
+// measurements are passed to and one that is closed when Run returns.
+func runReader(ctx context.Context, sr *SensorReader) (<-chan Measurement, <-chan struct{}) {
+	xs := make(chan Measurement, 16)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sr.Run(ctx, func(x Measurement) { xs <- x })
+	}()
+	return xs, done
+}
+
+func receive(t *testing.T, xs <-chan Measurement) Measurement {
+	select {
+	case x := <-xs:
+		return x
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a measurement")
+		return Measurement{}
+	}
+}
+
+func expectNone(t *testing.T, xs <-chan Measurement) {
+	select {
+	case x := <-xs:
+		t.Fatalf("unexpected measurement %s", x)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func started(t *testing.T, s *blockingSensor) {
+	select {
+	case <-s.started:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a read to start")
+	}
+}
+
+func notStarted(t *testing.T, s *blockingSensor) {
+	select {
+	case <-s.started:
+		t.Fatal("unexpected read")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// advance waits until the reader waits on the clock, and advances it by d.
+func advance(t *testing.T, clock *FakeClock, d time.Duration) {
+	waitFor(t, "reader to wait", func() bool { return clock.Waiters() != 0 })
+	clock.Advance(d)
+}
+
+func TestReaderCadence(t *testing.T) {
+	clock := NewFakeClock(readerStart)
+	s := &FakeSensor{Readings: []Reading{{Temperature: 21, Humidity: 45}, {Temperature: 22, Humidity: 46}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	xs, _ := runReader(ctx, &SensorReader{Sensor: s, Interval: time.Minute, Clock: clock})
+
+	for i := 0; i < 4; i++ {
+		x := receive(t, xs)
+		if want := readerStart.Add(time.Duration(i) * time.Minute).Unix(); x.UnixTime != want {
+			t.Errorf("measurement %d at %d, want %d", i, x.UnixTime, want)
+		}
+		if want := float32(21 + i%2); x.Temperature != want {
+			t.Errorf("measurement %d is %.1f °C, want %.1f °C", i, x.Temperature, want)
+		}
+		expectNone(t, xs)
+		advance(t, clock, time.Minute)
+	}
+}
+
+func TestReaderSkipsMissedTicks(t *testing.T) {
+	clock := NewFakeClock(readerStart)
+	s := newBlockingSensor()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	xs, _ := runReader(ctx, &SensorReader{Sensor: s, Interval: time.Minute, Clock: clock})
+
+	// The first read takes three and a half intervals.
+	started(t, s)
+	clock.Advance(3*time.Minute + 30*time.Second)
+	s.reading <- Reading{Temperature: 21, Humidity: 45}
+	receive(t, xs)
+
+	// The missed ticks are skipped, except for a single read right away.
+	started(t, s)
+	s.reading <- Reading{Temperature: 21, Humidity: 45}
+	if x := receive(t, xs); x.UnixTime != readerStart.Add(210*time.Second).Unix() {
+		t.Errorf("read at %d after the slow read", x.UnixTime-readerStart.Unix())
+	}
+	notStarted(t, s)
+
+	// Then the cadence continues at the next tick.
+	advance(t, clock, 30*time.Second)
+	started(t, s)
+	s.reading <- Reading{Temperature: 21, Humidity: 45}
+	if x := receive(t, xs); x.UnixTime != readerStart.Add(4*time.Minute).Unix() {
+		t.Errorf("read at %d, want at 240", x.UnixTime-readerStart.Unix())
+	}
+}
+
+func TestReaderTimeout(t *testing.T) {
+	clock := NewFakeClock(readerStart)
+	s := newBlockingSensor()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	xs, _ := runReader(ctx, &SensorReader{Sensor: s, Interval: time.Minute, Timeout: 30 * time.Second, Clock: clock})
+
+	// The read times out and is abandoned.
+	started(t, s)
+	advance(t, clock, 30*time.Second)
+
+	// While it is still pending, the next tick does not start another read.
+	advance(t, clock, 30*time.Second)
+	notStarted(t, s)
+
+	// Once it returns, its result is discarded and reads resume.
+	s.reading <- Reading{Temperature: 30, Humidity: 90}
+	expectNone(t, xs)
+	advance(t, clock, time.Minute)
+	started(t, s)
+	s.reading <- Reading{Temperature: 21, Humidity: 45}
+	if x := receive(t, xs); x.Temperature != 21 || x.UnixTime != readerStart.Add(2*time.Minute).Unix() {
+		t.Errorf("measurement after the timeout is %s", x)
+	}
+}
+
+func TestReaderCancel(t *testing.T) {
+	// Cancelled while waiting for the next tick.
+	clock := NewFakeClock(readerStart)
+	ctx, cancel := context.WithCancel(context.Background())
+	xs, done := runReader(ctx, &SensorReader{Sensor: &FakeSensor{Readings: []Reading{{}}}, Interval: time.Minute, Clock: clock})
+	receive(t, xs)
+	waitFor(t, "reader to wait", func() bool { return clock.Waiters() != 0 })
+	cancel()
+	<-done
+
+	// Cancelled while a read is in progress, which is not waited for.
+	s := newBlockingSensor()
+	ctx, cancel = context.WithCancel(context.Background())
+	xs, done = runReader(ctx, &SensorReader{Sensor: s, Interval: time.Minute, Clock: clock})
+	started(t, s)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("reader did not stop while a read was in progress")
+	}
+	go func() { s.reading <- Reading{} }()
+	expectNone(t, xs)
+}
+
+func TestReaderReplayFinished(t *testing.T) {
+	clock := NewFakeClock(readerStart)
+	s := &FakeSensor{Err: ErrReplayFinished}
+	_, done := runReader(context.Background(), &SensorReader{Sensor: s, Interval: time.Minute, Clock: clock})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("reader did not stop when the replay finished")
+	}
+}
+
+func TestReaderZeroInterval(t *testing.T) {
+	clock := NewFakeClock(readerStart)
+	s := &FakeSensor{Readings: []Reading{{Temperature: 21, Humidity: 45}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	xs, _ := runReader(ctx, &SensorReader{Sensor: s, Clock: clock})
+
+	receive(t, xs)
+	expectNone(t, xs)
+	advance(t, clock, minInterval)
+	if x := receive(t, xs); x.UnixTime != readerStart.Add(minInterval).Unix() {
+		t.Errorf("read at %d, want at %d", x.UnixTime-readerStart.Unix(), minInterval/time.Second)
+	}
+}
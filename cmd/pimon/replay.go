@@ -0,0 +1,119 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrReplayFinished is returned by a replay sensor once all measurements
+// have been replayed.
+var ErrReplayFinished = errors.New("replay finished")
+
+// replaySensor replays the measurements of a file in the format written by
+// the CSV persister, as if they came from a sensor. The time between the
+// measurements is preserved, but divided by the speed.
+//
+// Each read returns the most recent measurement that is due right away,
+// skipping any that were missed, just as a real sensor would. If no new
+// measurement is due, the last one is returned again, as of a sensor in
+// a steady climate, so that the interval of the reader sets the pace and
+// gaps in the recording do not look like a failing sensor. The time of
+// each reading is that of the replay's clock, which starts when the replay
+// does and runs speed times as fast as the real one, so at higher speeds
+// the readings are ahead of the clock; this is one reason why replayed
+// measurements are only kept in memory, unless a database is given
+// explicitly.
+//
+// Once all measurements have been replayed, reads return ErrReplayFinished,
+// at which the sensor reader stops instead of reporting a failure.
+type replaySensor struct {
+	sync.Mutex
+
+	name   string
+	series Series
+	speed  float64
+	start  time.Time
+	next   int
+
+	now func() time.Time
+}
+
+func newReplaySensor(sc SensorConfiguration) (Sensor, error) {
+	bs, err := ioutil.ReadFile(sc.Replay)
+	if err != nil {
+		return nil, err
+	}
+	var s Series
+	err = s.UnmarshalCSV(bs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read replay file %s: %v", sc.Replay, err)
+	}
+	return NewReplaySensor(filepath.Base(sc.Replay), s, sc.ReplaySpeed, time.Now)
+}
+
+// NewReplaySensor returns a sensor that replays s at the given speed,
+// where now provides the time.
+func NewReplaySensor(name string, s Series, speed float64, now func() time.Time) (Sensor, error) {
+	if s.Len() == 0 {
+		return nil, errors.New("no measurements to replay")
+	}
+	if speed <= 0 {
+		speed = 1
+	}
+	return &replaySensor{
+		name:   name,
+		series: s,
+		speed:  speed,
+		start:  now(),
+		now:    now,
+	}, nil
+}
+
+func (s *replaySensor) Read() (Reading, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.next >= s.series.Len() {
+		return Reading{}, ErrReplayFinished
+	}
+
+	// The first measurement is due at the start, so one is always due;
+	// if none has become due since the last read, i is the last one read.
+	now := s.now()
+	i := s.next - 1
+	if i < 0 {
+		i = 0
+	}
+	for i+1 < s.series.Len() && !s.due(i+1).After(now) {
+		i++
+	}
+	s.next = i + 1
+
+	x := s.series[i]
+	return Reading{
+		Time:        s.start.Add(time.Duration(float64(now.Sub(s.start)) * s.speed)),
+		Temperature: x.Temperature,
+		Humidity:    x.Humidity,
+		Pressure:    x.Pressure,
+	}, nil
+}
+
+// offset returns the time between the first and the i-th measurement.
+func (s *replaySensor) offset(i int) time.Duration {
+	return time.Duration(s.series[i].UnixTime-s.series[0].UnixTime) * time.Second
+}
+
+// due returns the time at which the i-th measurement is replayed.
+func (s *replaySensor) due(i int) time.Time {
+	return s.start.Add(time.Duration(float64(s.offset(i)) / s.speed))
+}
+
+func (s *replaySensor) String() string { return "replay:" + s.name }
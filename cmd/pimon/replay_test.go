@@ -0,0 +1,63 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	// An overnight gap of eight hours, replayed at twice the speed.
+	start := time.Date(2015, 11, 1, 22, 0, 0, 0, time.UTC).Unix()
+	s := Series{
+		{UnixTime: start, Temperature: 20, Humidity: 40},
+		{UnixTime: start + 60, Temperature: 21, Humidity: 41},
+		{UnixTime: start + 8*3600, Temperature: 22, Humidity: 42},
+	}
+	clock := NewFakeClock(readerStart)
+	r, err := NewReplaySensor("test", s, 2, clock.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(wantT float32, wantAt time.Duration) {
+		t.Helper()
+		done := make(chan struct{})
+		var x Reading
+		go func() {
+			defer close(done)
+			x, err = r.Read()
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("replay blocks in Read")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if x.Temperature != wantT || !x.Time.Equal(readerStart.Add(wantAt)) {
+			t.Errorf("Read() = %.1f °C at %s, want %.1f °C at %s",
+				x.Temperature, x.Time.Sub(readerStart), wantT, wantAt)
+		}
+	}
+
+	read(20, 0)
+	clock.Advance(30 * time.Second)
+	read(21, time.Minute)
+
+	// During the gap, the last measurement is read again at once.
+	for i := 1; i <= 3; i++ {
+		clock.Advance(time.Hour)
+		read(21, time.Minute+time.Duration(i)*2*time.Hour)
+	}
+	clock.Advance(time.Hour)
+	read(22, time.Minute+8*time.Hour)
+
+	if _, err := r.Read(); err != ErrReplayFinished {
+		t.Errorf("Read() after the end = %v, want ErrReplayFinished", err)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RetentionConfiguration defines how long measurements are kept, and when
+// they are rolled up into aggregates of their minimum, mean and maximum.
+// Since applying it rewrites the database, it is disabled by default.
+type RetentionConfiguration struct {
+	// Tiers are the resolutions that measurements are rolled up into as they
+	// age, from the youngest to the oldest. Measurements younger than the
+	// first tier are kept as they are.
+	Tiers []TierConfiguration `toml:"tier"`
+
+	// Drop is the age after which measurements are dropped completely.
+	// If it is zero, measurements are kept forever.
+	Drop time.Duration `toml:"drop"`
+}
+
+// TierConfiguration rolls up measurements older than After into one
+// aggregate per Resolution.
+type TierConfiguration struct {
+	After      time.Duration `toml:"after"`
+	Resolution time.Duration `toml:"resolution"`
+}
+
+// defaultRetention keeps all measurements. A sensible policy is to roll
+// measurements up into one a minute after a week, and into one an hour
+// after a month:
+//
+//	[[retention.tier]]
+//	after = "168h"
+//	resolution = "1m"
+//
+//	[[retention.tier]]
+//	after = "720h"
+//	resolution = "1h"
+var defaultRetention = RetentionConfiguration{}
+
+// retentionEvery is how often the retention policy is applied to the
+// measurements of a monitor, in terms of the time of the measurements.
+const retentionEvery = time.Hour
+
+// Validate returns an error if the tiers are not in order.
+func (rc RetentionConfiguration) Validate() error {
+	var last TierConfiguration
+	for i, t := range rc.Tiers {
+		if t.Resolution < time.Second {
+			return fmt.Errorf("retention tier %d: resolution must be at least a second", i+1)
+		}
+		if i > 0 && (t.After <= last.After || t.Resolution <= last.Resolution) {
+			return fmt.Errorf("retention tier %d: tiers must be ordered by age and resolution", i+1)
+		}
+		last = t
+	}
+	if rc.Drop < 0 {
+		return errors.New("retention drop age is invalid")
+	}
+	if rc.Drop > 0 && len(rc.Tiers) > 0 && rc.Drop <= last.After {
+		return errors.New("retention drop age must be beyond the last tier")
+	}
+	return nil
+}
+
+// resolution returns the resolution of measurements of the given age,
+// or zero if they are kept as they are.
+func (rc RetentionConfiguration) resolution(age time.Duration) time.Duration {
+	var r time.Duration
+	for _, t := range rc.Tiers {
+		if age >= t.After {
+			r = t.Resolution
+		}
+	}
+	return r
+}
+
+// Apply returns s with the measurements that are old enough rolled up into
+// aggregates, and those older than Drop removed, and whether anything
+// changed. The series s itself is not modified.
+//
+// An aggregate starts at a multiple of its resolution, so that applying the
+// policy again only merges measurements that have aged since into the
+// aggregates that already exist.
+func (rc RetentionConfiguration) Apply(s Series, now time.Time) (Series, bool) {
+	out := make(Series, 0, len(s))
+	changed := false
+	for _, x := range s {
+		age := now.Sub(time.Unix(x.UnixTime, 0))
+		if rc.Drop > 0 && age > rc.Drop {
+			changed = true
+			continue
+		}
+		span := int64(rc.resolution(age) / time.Second)
+		if span == 0 || x.Span >= span {
+			out = append(out, x)
+			continue
+		}
+
+		changed = true
+		start := x.UnixTime - x.UnixTime%span
+		if n := len(out); n != 0 && out[n-1].Span == span && out[n-1].UnixTime == start {
+			out[n-1].merge(x)
+			continue
+		}
+		out = append(out, x.aggregate(start, span))
+	}
+	return out, changed
+}
+
+// aggregate returns x as an aggregate that starts at start and covers span.
+func (x Measurement) aggregate(start, span int64) Measurement {
+	if x.Span == 0 {
+		x.Count = 1
+		x.MinTemperature, x.MaxTemperature = x.Temperature, x.Temperature
+		x.MinHumidity, x.MaxHumidity = x.Humidity, x.Humidity
+		if x.Pressure != 0 {
+			x.PressureCount = 1
+		}
+	} else if x.PressureCount == 0 && x.Pressure != 0 {
+		x.PressureCount = x.Count
+	}
+	x.UnixTime, x.Span = start, span
+	return x
+}
+
+// merge merges x, which may be an aggregate itself, into the aggregate a.
+// The pressure is the mean of only those measurements that had one.
+func (a *Measurement) merge(x Measurement) {
+	*a = a.aggregate(a.UnixTime, a.Span)
+	x = x.aggregate(x.UnixTime, 1)
+	n, m := float32(a.Count), float32(x.Count)
+	a.Temperature = (n*a.Temperature + m*x.Temperature) / (n + m)
+	a.Humidity = (n*a.Humidity + m*x.Humidity) / (n + m)
+	if pn, pm := float32(a.PressureCount), float32(x.PressureCount); pm != 0 {
+		a.Pressure = (pn*a.Pressure + pm*x.Pressure) / (pn + pm)
+	}
+	a.Count += x.Count
+	a.PressureCount += x.PressureCount
+	if x.MinTemperature < a.MinTemperature {
+		a.MinTemperature = x.MinTemperature
+	}
+	if x.MaxTemperature > a.MaxTemperature {
+		a.MaxTemperature = x.MaxTemperature
+	}
+	if x.MinHumidity < a.MinHumidity {
+		a.MinHumidity = x.MinHumidity
+	}
+	if x.MaxHumidity > a.MaxHumidity {
+		a.MaxHumidity = x.MaxHumidity
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetentionPressure(t *testing.T) {
+	rc := RetentionConfiguration{Tiers: []TierConfiguration{{After: time.Hour, Resolution: time.Hour}}}
+	start := time.Date(2015, 11, 1, 12, 0, 0, 0, time.UTC).Unix()
+	s := Series{
+		{UnixTime: start, Temperature: 20, Humidity: 40, Pressure: 1000},
+		{UnixTime: start + 60, Temperature: 21, Humidity: 41},
+		{UnixTime: start + 120, Temperature: 22, Humidity: 42},
+		{UnixTime: start + 180, Temperature: 23, Humidity: 43, Pressure: 1010},
+	}
+	out, changed := rc.Apply(s, time.Unix(start, 0).Add(3*time.Hour))
+	if !changed || out.Len() != 1 {
+		t.Fatalf("Apply() = %v, %v", out, changed)
+	}
+	a := out[0]
+	if a.Count != 4 || a.PressureCount != 2 || a.Pressure != 1005 || a.Temperature != 21.5 {
+		t.Errorf("aggregate is %+v, want the pressure of 2 of 4 measurements", a)
+	}
+
+	// The pressure count survives the database.
+	var x Measurement
+	if err := x.UnmarshalRecord(a.MarshalRecord()); err != nil || x != a {
+		t.Errorf("record %q read as %+v, %v", a.MarshalRecord(), x, err)
+	}
+
+	// Older aggregates without a pressure count have a pressure for each
+	// measurement.
+	old := Measurement{UnixTime: start, Temperature: 20, Humidity: 40, Pressure: 1000,
+		Span: 3600, Count: 3, MinTemperature: 20, MaxTemperature: 20, MinHumidity: 40, MaxHumidity: 40}
+	old.merge(Measurement{UnixTime: start + 60, Temperature: 20, Humidity: 40, Pressure: 1004})
+	if old.Count != 4 || old.PressureCount != 4 || old.Pressure != 1001 {
+		t.Errorf("merged older aggregate is %+v", old)
+	}
+}
+
+func TestAggregateCSV(t *testing.T) {
+	start := time.Date(2015, 11, 1, 12, 0, 0, 0, time.Local).Unix()
+	s := Series{
+		{UnixTime: start, Temperature: 20, Humidity: 40, Pressure: 1005, Span: 3600, Count: 4, PressureCount: 2,
+			MinTemperature: 19, MaxTemperature: 21, MinHumidity: 39, MaxHumidity: 41},
+		{UnixTime: start + 3600, Temperature: 21, Humidity: 41, Span: 3600, Count: 2,
+			MinTemperature: 21, MaxTemperature: 21, MinHumidity: 41, MaxHumidity: 41},
+		{UnixTime: start + 7200, Temperature: 22, Humidity: 42, Pressure: 1010},
+		{UnixTime: start + 7260, Temperature: 22, Humidity: 42},
+	}
+	bs, err := s.MarshalCSV()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every record has as many fields as the header.
+	rs, err := csv.NewReader(bytes.NewReader(bs)).ReadAll()
+	if err != nil {
+		t.Fatalf("exported CSV is not rectangular: %v\n%s", err, bs)
+	}
+	if h := rs[0]; h[len(h)-1] != "pressure_count" {
+		t.Errorf("header %q has no pressure count", h)
+	}
+
+	var got Series
+	if err := got.UnmarshalCSV(bs); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, s) {
+		t.Errorf("read back %v, want %v", got, s)
+	}
+}
+
+func TestRetentionDefault(t *testing.T) {
+	s := Series{{UnixTime: 1000, Temperature: 20, Humidity: 40}}
+	if _, changed := defaultRetention.Apply(s, time.Now()); changed {
+		t.Error("default retention changes the database")
+	}
+}
+
+// blockingPersister blocks in Rewrite until it is released.
+type blockingPersister struct {
+	memoryPersister
+	rewriting chan struct{}
+	release   chan struct{}
+}
+
+func (p *blockingPersister) Rewrite(s Series) error {
+	p.rewriting <- struct{}{}
+	<-p.release
+	return nil
+}
+
+func TestMonitorRewriteUnlocked(t *testing.T) {
+	defer func(c Configuration) { *Conf = c }(*Conf)
+	Conf.Conserve = false
+	Conf.Retention = RetentionConfiguration{Drop: time.Hour}
+
+	p := &blockingPersister{rewriting: make(chan struct{}), release: make(chan struct{})}
+	m, err := NewMonitor(p, &emaEstimator{lag: 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Unix()
+	m.Update(Measurement{UnixTime: start, Temperature: 20, Humidity: 40})
+
+	// Two hours later, the first measurement is dropped.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Update(Measurement{UnixTime: start + 7200, Temperature: 20, Humidity: 40})
+	}()
+	select {
+	case <-p.rewriting:
+	case <-time.After(time.Second):
+		t.Fatal("retention did not rewrite the database")
+	}
+
+	read := make(chan Series)
+	go func() { read <- m.Series() }()
+	select {
+	case s := <-read:
+		if s.Len() != 1 {
+			t.Errorf("series during the rewrite has %d measurements, want 1", s.Len())
+		}
+	case <-time.After(time.Second):
+		t.Error("series cannot be read during the rewrite")
+	}
+	close(p.release)
+	<-done
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/d2r2/go-dht"
+)
+
+// Reading is the result of reading a sensor once.
+type Reading struct {
+	// Time is when the reading was taken; if it is zero, the time
+	// the reading was received is used.
+	Time time.Time
+
+	Temperature float32
+	Humidity    float32
+	// Pressure is the air pressure in hPa, or zero if unsupported.
+	Pressure float32
+
+	// Retries is the number of times the driver had to retry the read,
+	// and ChecksumFailures how many of the retried attempts failed the
+	// checksum.
+	Retries          int
+	ChecksumFailures int
+	// Duration is how long the read took.
+	Duration time.Duration
+}
+
+// Sensor is a temperature and humidity sensor.
+type Sensor interface {
+	// Read reads the sensor once; if the read fails, an error is returned.
+	Read() (Reading, error)
+
+	// String returns the driver and where the sensor is attached,
+	// such as "DHT22@4", or "SHT31@1:0x44" for bus 1 and address 0x44.
+	String() string
+}
+
+type SensorConfiguration struct {
+	// Driver is the kind of sensor, such as "dht22".
+	Driver string `toml:"driver"`
+	// Pin is the GPIO pin the sensor is attached to.
+	Pin int `toml:"pin"`
+	// I2CBus and I2CAddress define where an I2C sensor is attached;
+	// if the address is zero, the default address of the sensor is used.
+	I2CBus     int `toml:"i2c_bus"`
+	I2CAddress int `toml:"i2c_address"`
+	// Simulation describes the climate for the simulated sensor;
+	// if it is nil, the default simulation is used.
+	Simulation *SimulationConfiguration `toml:"simulation"`
+	// Replay is the file of measurements that the replay sensor reads,
+	// and ReplaySpeed how many times faster than real time it does so.
+	Replay      string  `toml:"replay"`
+	ReplaySpeed float64 `toml:"replay_speed"`
+	// Command is the command and its arguments that the exec sensor runs,
+	// and Timeout how long it may run.
+	Command []string      `toml:"command"`
+	Timeout time.Duration `toml:"timeout"`
+	// Weight is how much the sensor counts when its readings are fused
+	// with those of other sensors by mean; if it is zero, 1 is used.
+	Weight float64 `toml:"weight"`
+}
+
+// sensorDrivers contains the constructors of all sensor drivers by name.
+var sensorDrivers = map[string]func(SensorConfiguration) (Sensor, error){
+	"dht11":     newDHTSensor(dht.DHT11),
+	"dht22":     newDHTSensor(dht.DHT22),
+	"am2302":    newDHTSensor(dht.AM2302),
+	"bme280":    newBME280Sensor,
+	"sht31":     newSHT31Sensor,
+	"fake":      newFakeSensor,
+	"simulated": newSimulatedSensor,
+	"replay":    newReplaySensor,
+	"exec":      newExecSensor,
+	"remote":    newRemoteSensor,
+}
+
+// SensorDrivers returns the names of all sensor drivers in sorted order.
+func SensorDrivers() []string {
+	names := make([]string, 0, len(sensorDrivers))
+	for k := range sensorDrivers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate returns an error if the configuration is not usable.
+func (sc SensorConfiguration) Validate() error {
+	driver := strings.ToLower(sc.Driver)
+	if _, ok := sensorDrivers[driver]; !ok {
+		return fmt.Errorf("unknown sensor %q, expecting one of %s", sc.Driver, strings.Join(SensorDrivers(), ", "))
+	}
+	if strings.HasPrefix(driver, "dht") || driver == "am2302" {
+		if sc.Pin <= 0 {
+			return fmt.Errorf("sensor pin unspecified")
+		}
+	}
+	if driver == "replay" && sc.Replay == "" {
+		return fmt.Errorf("replay file unspecified")
+	}
+	if driver == "exec" && len(sc.Command) == 0 {
+		return fmt.Errorf("sensor command unspecified")
+	}
+	if sc.I2CAddress < 0 || sc.I2CAddress > 0x7F {
+		return fmt.Errorf("invalid I2C address %#x", sc.I2CAddress)
+	}
+	if sc.Weight < 0 {
+		return fmt.Errorf("invalid sensor weight %v", sc.Weight)
+	}
+	return nil
+}
+
+// NewSensor returns the sensor described by sc.
+func NewSensor(sc SensorConfiguration) (Sensor, error) {
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+	return sensorDrivers[strings.ToLower(sc.Driver)](sc)
+}
+
+// DHT Sensor {{{
+
+// The DHT sensors often fail to deliver their data intact, so each read
+// makes up to dhtAttempts attempts, waiting dhtRetryDelay in between, as
+// the sensors cannot be read more than about once every two seconds.
+const (
+	dhtAttempts   = 10
+	dhtRetryDelay = 2 * time.Second
+)
+
+// dhtSensor reads a DHT11 or DHT22 (AM2302) sensor over a single GPIO pin.
+type dhtSensor struct {
+	typ dht.SensorType
+	pin int
+
+	// read reads the sensor once, and sleep waits before a retry.
+	read  func(dht.SensorType, int, bool) (float32, float32, error)
+	sleep func(time.Duration)
+}
+
+func newDHTSensor(typ dht.SensorType) func(SensorConfiguration) (Sensor, error) {
+	return func(sc SensorConfiguration) (Sensor, error) {
+		return &dhtSensor{typ: typ, pin: sc.Pin, read: dht.ReadDHTxx, sleep: time.Sleep}, nil
+	}
+}
+
+func (s *dhtSensor) Read() (Reading, error) {
+	before := time.Now()
+	var r Reading
+	for i := 0; ; i++ {
+		t, h, err := s.read(s.typ, s.pin, false)
+		if err == nil {
+			r.Temperature, r.Humidity = t, h
+			r.Duration = time.Since(before)
+			return r, nil
+		}
+		if isDHTChecksumError(err) {
+			err = ErrChecksum
+		}
+		if i+1 == dhtAttempts {
+			r.Duration = time.Since(before)
+			return r, err
+		}
+		r.Retries++
+		if err == ErrChecksum {
+			r.ChecksumFailures++
+		}
+		s.sleep(dhtRetryDelay)
+	}
+}
+
+// isDHTChecksumError returns whether err from the DHT driver is due to
+// a failed checksum, which the driver only describes as
+// "Control sum %d doesn't match %d (%d+%d+%d+%d)".
+func isDHTChecksumError(err error) bool {
+	return strings.HasPrefix(strings.ToLower(err.Error()), "control sum ")
+}
+
+func (s *dhtSensor) String() string { return fmt.Sprintf("%s@%d", s.typ, s.pin) }
+
+// }}}
+
+// Fake Sensor {{{
+
+// FakeSensor returns the readings it is given in turn, starting over
+// once it reaches the end. If Err is set, it is returned instead.
+type FakeSensor struct {
+	sync.Mutex
+
+	Readings []Reading
+	Err      error
+	i        int
+}
+
+func newFakeSensor(sc SensorConfiguration) (Sensor, error) {
+	return &FakeSensor{Readings: []Reading{{Temperature: 21, Humidity: 48}}}, nil
+}
+
+func (s *FakeSensor) Read() (Reading, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.Err != nil {
+		return Reading{}, s.Err
+	}
+	if len(s.Readings) == 0 {
+		return Reading{}, fmt.Errorf("no fake readings")
+	}
+	r := s.Readings[s.i%len(s.Readings)]
+	s.i++
+	return r, nil
+}
+
+func (s *FakeSensor) String() string { return "fake" }
+
+// }}}
@@ -0,0 +1,85 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/d2r2/go-dht"
+)
+
+// newFakeDHT returns a DHT sensor whose attempts return errs in turn,
+// and succeed once they run out.
+func newFakeDHT(errs ...error) (*dhtSensor, *int) {
+	var attempts int
+	s := &dhtSensor{
+		typ: dht.DHT22,
+		pin: 4,
+		read: func(dht.SensorType, int, bool) (float32, float32, error) {
+			attempts++
+			if attempts <= len(errs) {
+				return 0, 0, errs[attempts-1]
+			}
+			return 21, 45, nil
+		},
+		sleep: func(time.Duration) {},
+	}
+	return s, &attempts
+}
+
+// The errors as the DHT driver reports them.
+var (
+	errDHTChecksum = fmt.Errorf("Control sum %d doesn't match %d (%d+%d+%d+%d)", 12, 13, 1, 2, 3, 7)
+	errDHTTimeout  = fmt.Errorf("Error during call C.dial_DHTxx_and_read()")
+)
+
+func TestDHTRetries(t *testing.T) {
+	s, attempts := newFakeDHT(errDHTChecksum, errDHTTimeout, errDHTChecksum)
+	r, err := s.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Temperature != 21 || r.Humidity != 45 || *attempts != 4 {
+		t.Errorf("Read() = %.1f °C, %.1f %% after %d attempts", r.Temperature, r.Humidity, *attempts)
+	}
+	if r.Retries != 3 || r.ChecksumFailures != 2 {
+		t.Errorf("Read() retried %d times with %d checksum failures, want 3 and 2", r.Retries, r.ChecksumFailures)
+	}
+}
+
+func TestDHTFailure(t *testing.T) {
+	errs := make([]error, dhtAttempts)
+	for i := range errs {
+		errs[i] = errDHTTimeout
+	}
+	errs[0], errs[dhtAttempts-1] = errDHTChecksum, errDHTChecksum
+	s, attempts := newFakeDHT(errs...)
+	r, err := s.Read()
+	if err != ErrChecksum {
+		t.Errorf("Read() error %v, want ErrChecksum", err)
+	}
+	if *attempts != dhtAttempts || r.Retries != dhtAttempts-1 || r.ChecksumFailures != 1 {
+		t.Errorf("Read() made %d attempts, %d retries with %d checksum failures", *attempts, r.Retries, r.ChecksumFailures)
+	}
+
+	// The health counts each attempt that failed the checksum.
+	s, _ = newFakeDHT(errs...)
+	ss := WithStats(s, time.Now)
+	ss.Read()
+	h := ss.(HealthReporter).Health()[0]
+	if h.Attempts != 1 || h.Failures != 1 || h.Retries != dhtAttempts-1 || h.ChecksumFailures != 2 {
+		t.Errorf("health after a failed read is %+v", h)
+	}
+
+	for i := range errs {
+		errs[i] = errDHTTimeout
+	}
+	s, _ = newFakeDHT(errs...)
+	if _, err := s.Read(); err != errDHTTimeout {
+		t.Errorf("Read() error %v, want the driver's error", err)
+	}
+}
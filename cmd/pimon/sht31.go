@@ -0,0 +1,84 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const sht31Address = 0x44
+
+// sht31Measure starts a single shot measurement with high repeatability
+// and without clock stretching, which takes at most 15ms.
+var sht31Measure = []byte{0x24, 0x00}
+
+// sht31Sensor reads temperature and humidity from a Sensirion SHT31 over I2C.
+type sht31Sensor struct {
+	bus  I2CBus
+	n    int
+	addr byte
+}
+
+func newSHT31Sensor(sc SensorConfiguration) (Sensor, error) {
+	bus, err := openI2CBus(sc.I2CBus)
+	if err != nil {
+		return nil, err
+	}
+	return NewSHT31Sensor(bus, sc.I2CBus, byte(sc.I2CAddress)), nil
+}
+
+// NewSHT31Sensor returns a driver for the SHT31 at addr on bus, which has
+// the number n; if addr is zero, the default address 0x44 is used.
+func NewSHT31Sensor(bus I2CBus, n int, addr byte) Sensor {
+	if addr == 0 {
+		addr = sht31Address
+	}
+	return &sht31Sensor{bus, n, addr}
+}
+
+func (s *sht31Sensor) Read() (Reading, error) {
+	before := time.Now()
+	err := s.bus.WriteBytes(s.addr, sht31Measure)
+	if err != nil {
+		return Reading{}, err
+	}
+	time.Sleep(15 * time.Millisecond)
+
+	data, err := s.bus.ReadBytes(s.addr, 6)
+	if err != nil {
+		return Reading{}, err
+	}
+	if sht31CRC(data[0:2]) != data[2] || sht31CRC(data[3:5]) != data[5] {
+		return Reading{}, ErrChecksum
+	}
+
+	t := uint16(data[0])<<8 | uint16(data[1])
+	h := uint16(data[3])<<8 | uint16(data[4])
+	return Reading{
+		Temperature: -45 + 175*float32(t)/65535,
+		Humidity:    100 * float32(h) / 65535,
+		Duration:    time.Since(before),
+	}, nil
+}
+
+func (s *sht31Sensor) String() string { return fmt.Sprintf("SHT31@%d:%#x", s.n, s.addr) }
+
+// sht31CRC computes the CRC-8 checksum of the SHT31, which uses
+// the polynomial 0x31 with an initial value of 0xFF.
+func sht31CRC(data []byte) byte {
+	crc := byte(0xFF)
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x31
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
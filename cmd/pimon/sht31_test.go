@@ -0,0 +1,58 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestSHT31CRC(t *testing.T) {
+	// The example from the datasheet.
+	if crc := sht31CRC([]byte{0xBE, 0xEF}); crc != 0x92 {
+		t.Errorf("CRC of 0xBEEF = %#x, want 0x92", crc)
+	}
+}
+
+// sht31Response returns the bytes the SHT31 sends for the raw values.
+func sht31Response(t, h uint16) []byte {
+	b := []byte{byte(t >> 8), byte(t), 0, byte(h >> 8), byte(h), 0}
+	b[2], b[5] = sht31CRC(b[0:2]), sht31CRC(b[3:5])
+	return b
+}
+
+func TestSHT31Read(t *testing.T) {
+	bus := NewFakeI2CBus()
+	bus.Responses[0x45] = sht31Response(0x6666, 0x8000)
+	s := NewSHT31Sensor(bus, 1, 0x45)
+	if s.String() != "SHT31@1:0x45" {
+		t.Errorf("String() = %q", s)
+	}
+
+	r, err := s.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(float64(r.Temperature)-25) > 0.01 || math.Abs(float64(r.Humidity)-50) > 0.01 {
+		t.Errorf("Read() = %.2f °C, %.2f %%, want 25 °C, 50 %%", r.Temperature, r.Humidity)
+	}
+	if w := bus.Written[0x45]; len(w) != 1 || !bytes.Equal(w[0], sht31Measure) {
+		t.Errorf("written %x, want the measurement command", w)
+	}
+
+	for _, i := range []int{1, 4} {
+		bad := sht31Response(0x6666, 0x8000)
+		bad[i] ^= 0x01
+		bus.Responses[0x45] = bad
+		if _, err := s.Read(); err != ErrChecksum {
+			t.Errorf("corrupted byte %d: error %v, want ErrChecksum", i, err)
+		}
+	}
+
+	if _, err := NewSHT31Sensor(bus, 0, 0).Read(); err == nil {
+		t.Error("read from an empty address succeeded")
+	}
+}
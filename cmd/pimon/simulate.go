@@ -0,0 +1,133 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type SimulationConfiguration struct {
+	// Temperature and Humidity are the daily means; the temperature peaks
+	// in the afternoon by TemperatureSwing, when the humidity is lowest
+	// by HumiditySwing.
+	Temperature      float32 `toml:"temperature"`
+	TemperatureSwing float32 `toml:"temperature_swing"`
+	Humidity         float32 `toml:"humidity"`
+	HumiditySwing    float32 `toml:"humidity_swing"`
+
+	// Noise is the standard deviation of the random noise that is added
+	// to each reading, in °C and %RH respectively.
+	Noise float32 `toml:"noise"`
+
+	// Excursions are periods where the humidity leaves the normal range,
+	// such as into a danger band.
+	Excursions []ExcursionConfiguration `toml:"excursion"`
+
+	// Seed seeds the noise; if it is zero, the current time is used.
+	Seed int64 `toml:"seed"`
+}
+
+// ExcursionConfiguration describes a recurring excursion of the humidity.
+// Starting at Offset after the simulation starts, and then again after each
+// Every, the humidity moves towards Humidity and back again over Duration.
+type ExcursionConfiguration struct {
+	Offset   time.Duration `toml:"offset"`
+	Every    time.Duration `toml:"every"`
+	Duration time.Duration `toml:"duration"`
+	Humidity float32       `toml:"humidity"`
+}
+
+var defaultSimulation = SimulationConfiguration{
+	Temperature:      21,
+	TemperatureSwing: 2,
+	Humidity:         48,
+	HumiditySwing:    4,
+	Noise:            0.2,
+}
+
+// simulatedSensor produces realistic readings without any hardware.
+type simulatedSensor struct {
+	sync.Mutex
+
+	conf  SimulationConfiguration
+	rand  *rand.Rand
+	start time.Time
+	now   func() time.Time
+}
+
+func newSimulatedSensor(sc SensorConfiguration) (Sensor, error) {
+	conf := defaultSimulation
+	if sc.Simulation != nil {
+		conf = *sc.Simulation
+	}
+	return NewSimulatedSensor(conf, time.Now), nil
+}
+
+// NewSimulatedSensor returns a sensor that simulates the climate described
+// by conf, where now returns the time of the simulation.
+func NewSimulatedSensor(conf SimulationConfiguration, now func() time.Time) Sensor {
+	seed := conf.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &simulatedSensor{
+		conf:  conf,
+		rand:  rand.New(rand.NewSource(seed)),
+		start: now(),
+		now:   now,
+	}
+}
+
+func (s *simulatedSensor) Read() (Reading, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := s.now()
+	c := s.conf
+
+	// The daily cycle peaks at 15:00 local time.
+	h := float64(now.Hour()) + float64(now.Minute())/60
+	day := float32(math.Sin(2 * math.Pi * (h - 9) / 24))
+	t := c.Temperature + c.TemperatureSwing*day
+	rh := c.Humidity - c.HumiditySwing*day
+
+	elapsed := now.Sub(s.start)
+	for _, e := range c.Excursions {
+		if w := e.weight(elapsed); w > 0 {
+			rh = (1-w)*rh + w*e.Humidity
+		}
+	}
+
+	t += c.Noise * float32(s.rand.NormFloat64())
+	rh += c.Noise * float32(s.rand.NormFloat64())
+	if rh < 0 {
+		rh = 0
+	} else if rh > 100 {
+		rh = 100
+	}
+	return Reading{Temperature: t, Humidity: rh}, nil
+}
+
+func (s *simulatedSensor) String() string { return "simulated" }
+
+// weight returns how far the excursion has progressed at the time elapsed
+// since the start, from 0 outside of it to 1 at its midpoint.
+func (e ExcursionConfiguration) weight(elapsed time.Duration) float32 {
+	if e.Duration <= 0 || elapsed < e.Offset {
+		return 0
+	}
+	d := elapsed - e.Offset
+	if e.Every > 0 {
+		d %= e.Every
+	}
+	if d >= e.Duration {
+		return 0
+	}
+	w := math.Sin(math.Pi * float64(d) / float64(e.Duration))
+	return float32(w * w)
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cassava/pillr/guitar"
+	"github.com/cassava/pillr/led"
+)
+
+// waitFor polls cond until it is true, failing the test after a second.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for ", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestSimulatedPipeline runs the whole pipeline without any hardware: the
+// simulated sensor is read on a fake clock, and the warning LED blinks on
+// a fake pin when the simulated humidity drops into a danger band.
+func TestSimulatedPipeline(t *testing.T) {
+	defer func(c Configuration) { *Conf = c }(*Conf)
+	Conf.Mail = MailConfiguration{}
+	Conf.Conserve = false
+
+	clock := NewFakeClock(time.Date(2015, 11, 1, 6, 0, 0, 0, time.Local))
+	sim := NewSimulatedSensor(SimulationConfiguration{
+		Temperature: 21,
+		Humidity:    48,
+		Noise:       0.1,
+		Seed:        1,
+		Excursions: []ExcursionConfiguration{
+			{Offset: time.Hour, Duration: 4 * time.Hour, Humidity: 15},
+		},
+	}, clock.Now)
+
+	p, err := NewPersister("memory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewMonitor(p, &emaEstimator{lag: 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pin := &led.FakePin{}
+	in := &Instrument{
+		Name:     "test",
+		Sensor:   sim,
+		Levels:   guitar.Larrivee,
+		Filter:   NewFilter(Conf.Filter, time.Minute),
+		Monitor:  m,
+		Warning:  &WarningLED{LED: led.NewWithPin(pin), Threat: guitar.Low},
+		Notifier: &Notifier{Levels: guitar.Larrivee, Monitor: m},
+		lastGood: clock.Now(),
+	}
+	defer in.Close()
+
+	var mu sync.Mutex
+	reads := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	sr := &SensorReader{Sensor: sim, Interval: time.Minute, Clock: clock}
+	go func() {
+		defer close(done)
+		sr.Run(ctx, func(x Measurement) {
+			in.Update(x)
+			mu.Lock()
+			reads++
+			mu.Unlock()
+		})
+	}()
+
+	// step advances the simulation by a minute and waits for the reading.
+	step := func() {
+		mu.Lock()
+		want := reads + 1
+		mu.Unlock()
+		waitFor(t, "reader to wait", func() bool { return clock.Waiters() == 1 })
+		clock.Advance(time.Minute)
+		waitFor(t, "reading", func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return reads == want
+		})
+	}
+
+	waitFor(t, "first reading", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return reads == 1
+	})
+	for i := 0; i < 30; i++ {
+		step()
+	}
+	if d := in.Danger(); d != guitar.Low {
+		t.Fatalf("danger before the excursion = %s, want low", d)
+	}
+	if pin.Toggles() != 0 {
+		t.Fatal("LED blinks without danger")
+	}
+
+	// Halfway through the excursion, the humidity is near 15%.
+	for i := 0; i < 3*60; i++ {
+		step()
+	}
+	if d := in.Danger(); d < guitar.High {
+		t.Fatalf("danger in the excursion = %s at %s, want at least high", d, m.Series().Top())
+	}
+	waitFor(t, "LED to blink", func() bool { return pin.Toggles() > 1 })
+
+	// After the excursion, the danger passes and the LED goes dark.
+	for i := 0; i < 3*60; i++ {
+		step()
+	}
+	if d := in.Danger(); d != guitar.Low {
+		t.Fatalf("danger after the excursion = %s, want low", d)
+	}
+	if pin.On() {
+		t.Error("LED still on after the danger passed")
+	}
+
+	cancel()
+	<-done
+	if n := m.Series().Len(); n != reads {
+		t.Errorf("series has %d measurements, want %d", n, reads)
+	}
+	if r := in.Filter.Rejected(); r != 0 {
+		t.Errorf("%d simulated measurements rejected", r)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Status command {{{
+
+var statusServer string
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "show health of sensors",
+	Long: `Show the health of the sensors of all instruments, as reported by
+the running pimon over its online access. A sensor that needs many retries,
+often fails its checksum or disagrees with the others is likely dying.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		url := statusServer
+		if url == "" {
+			url = serverURL(Conf.Listen)
+		}
+		hs, err := fetchHealth(url)
+		exitIf(err)
+		writeHealth(os.Stdout, hs)
+	},
+}
+
+func statusInit() {
+	statusCmd.Flags().StringVarP(&statusServer, "server", "s", "", "address of the running pimon, such as http://pi:8080")
+}
+
+// serverURL returns the URL at which a pimon listening on listen
+// can be reached from the same host.
+func serverURL(listen string) string {
+	if strings.HasPrefix(listen, ":") {
+		listen = "localhost" + listen
+	}
+	return "http://" + listen
+}
+
+func fetchHealth(url string) ([]Health, error) {
+	resp, err := http.Get(strings.TrimSuffix(url, "/") + "/health")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot get health from %s: %s", url, resp.Status)
+	}
+	var hs []Health
+	err = json.NewDecoder(resp.Body).Decode(&hs)
+	return hs, err
+}
+
+func writeHealth(w io.Writer, hs []Health) {
+	for _, h := range hs {
+		state := "ok"
+		if h.Failed {
+			state = "SENSOR FAILURE"
+		}
+		age := time.Duration(h.Age * float64(time.Second)).Round(time.Second)
+		fmt.Fprintf(w, "%s: %s, last measurement %s ago, %d rejected\n", h.Instrument, state, age, h.Rejected)
+
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "  SENSOR\tHEALTH\tATTEMPTS\tFAILURES\tCHECKSUM\tRETRIES\tOUTLIERS\tLATENCY\tLAST ERROR")
+		for _, s := range h.Sensors {
+			health := "healthy"
+			if !s.Healthy {
+				health = "unhealthy"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%d\t%d\t%d\t%d\t%d\t%.1fs/%.1fs\t%s\n", s.Sensor, health,
+				s.Attempts, s.Failures, s.ChecksumFailures, s.Retries, s.Outliers,
+				s.MeanLatency, s.MaxLatency, s.LastError)
+		}
+		tw.Flush()
+	}
+}
+
+// }}}
@@ -10,28 +10,90 @@ import (
 	"net/http"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/cassava/pillr/guitar"
 )
 
-var monitor *Monitor
+var instruments []*Instrument
 
 func init() {
+	http.HandleFunc("/instruments", serveInstruments)
 	http.HandleFunc("/series", serveSeries)
 	http.HandleFunc("/belief", serveBelief)
 	http.HandleFunc("/latest", serveLatest)
+	http.HandleFunc("/status", serveStatus)
+	http.HandleFunc("/health", serveHealth)
+	http.HandleFunc("/ingest", serveIngest)
 }
 
-func Serve(listen string, m *Monitor) {
-	monitor = m
+func Serve(listen string, is []*Instrument) {
+	instruments = is
 	err := http.ListenAndServe(listen, nil)
 	if err != nil {
 		log.Errorln(err)
 	}
 }
 
-func serveSeries(w http.ResponseWriter, r *http.Request) { serveStruct(w, r, monitor.Series()) }
-func serveBelief(w http.ResponseWriter, r *http.Request) { serveStruct(w, r, monitor.Belief()) }
+// instrument returns the instrument selected by the query parameter
+// instrument, or the first instrument if none is selected.
+// If the instrument does not exist, an error is served and nil returned.
+func instrument(w http.ResponseWriter, r *http.Request) *Instrument {
+	name := r.URL.Query().Get("instrument")
+	for _, in := range instruments {
+		if name == "" || in.Name == name {
+			return in
+		}
+	}
+	http.Error(w, "instrument unknown", 404)
+	return nil
+}
+
+type instrumentInfo struct {
+	Name    string        `json:"name"`
+	Profile string        `json:"profile"`
+	Danger  guitar.Danger `json:"danger"`
+	Failed  bool          `json:"sensor_failure"`
+}
+
+// serveInstruments lists all instruments, or with the query parameter
+// danger, only those in at least that danger.
+func serveInstruments(w http.ResponseWriter, r *http.Request) {
+	var min guitar.Danger
+	if q := r.URL.Query().Get("danger"); q != "" {
+		err := min.UnmarshalText([]byte(q))
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+	}
+
+	infos := make([]instrumentInfo, 0, len(instruments))
+	for _, in := range instruments {
+		if d := in.Danger(); d >= min {
+			failed, _ := in.Stale()
+			infos = append(infos, instrumentInfo{in.Name, in.Profile, d, failed})
+		}
+	}
+	serveStruct(w, r, infos)
+}
+
+func serveSeries(w http.ResponseWriter, r *http.Request) {
+	if in := instrument(w, r); in != nil {
+		serveStruct(w, r, in.Monitor.Series())
+	}
+}
+
+func serveBelief(w http.ResponseWriter, r *http.Request) {
+	if in := instrument(w, r); in != nil {
+		serveStruct(w, r, in.Monitor.Belief())
+	}
+}
+
 func serveLatest(w http.ResponseWriter, r *http.Request) {
-	s := monitor.Series()
+	in := instrument(w, r)
+	if in == nil {
+		return
+	}
+	s := in.Monitor.Series()
 	if len(s) == 0 {
 		http.Error(w, "no measurement data", 500)
 		return
@@ -39,6 +101,29 @@ func serveLatest(w http.ResponseWriter, r *http.Request) {
 	serveStruct(w, r, s.Top())
 }
 
+func serveStatus(w http.ResponseWriter, r *http.Request) {
+	if in := instrument(w, r); in != nil {
+		serveStruct(w, r, in.Status())
+	}
+}
+
+// serveHealth serves the health of the sensors of all instruments,
+// or of the instrument selected by the query parameter instrument.
+func serveHealth(w http.ResponseWriter, r *http.Request) {
+	hs := make([]Health, 0, len(instruments))
+	name := r.URL.Query().Get("instrument")
+	for _, in := range instruments {
+		if name == "" || in.Name == name {
+			hs = append(hs, in.Health())
+		}
+	}
+	if len(hs) == 0 && name != "" {
+		http.Error(w, "instrument unknown", 404)
+		return
+	}
+	serveStruct(w, r, hs)
+}
+
 type csvMarshaler interface {
 	MarshalCSV() ([]byte, error)
 }
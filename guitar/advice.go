@@ -0,0 +1,78 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package guitar
+
+// Direction is the direction in which a metric is moving.
+type Direction int
+
+const (
+	Steady Direction = iota
+	Rising
+	Falling
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Steady:
+		return "steady"
+	case Rising:
+		return "rising"
+	case Falling:
+		return "falling"
+	default:
+		return "n/a"
+	}
+}
+
+// Heading returns the direction for a rate of change, where any rate
+// whose magnitude is below tolerance is considered steady.
+func Heading(rate, tolerance float32) Direction {
+	switch {
+	case rate >= tolerance:
+		return Rising
+	case rate <= -tolerance:
+		return Falling
+	default:
+		return Steady
+	}
+}
+
+// Outlook contains the advice that depends on the direction of the trend,
+// which is given in addition to the remedy of the current band.
+type Outlook struct {
+	Worsening string // outside the safe range and moving away from it
+	Improving string // outside the safe range and moving towards it
+	Leaving   string // inside the safe range and moving towards its edge
+}
+
+// Recommend returns the advice for the value v moving in the direction dir.
+// The advice on the trend comes first, followed by the remedy of the band.
+func (l Levels) Recommend(v float32, dir Direction) []string {
+	if l.Details == nil {
+		return nil
+	}
+
+	var advice []string
+	add := func(s string) {
+		if s != "" {
+			advice = append(advice, s)
+		}
+	}
+
+	o := l.Details.Outlook
+	low, high := l.Safe()
+	switch {
+	case v < low && dir == Falling, v >= high && dir == Rising:
+		add(o.Worsening)
+	case v < low && dir == Rising, v >= high && dir == Falling:
+		add(o.Improving)
+	case dir == Falling && v-low < (high-low)/4, dir == Rising && high-v < (high-low)/4:
+		add(o.Leaving)
+	}
+	if i := l.Band(v); i < len(l.Details.Remedies) {
+		add(l.Details.Remedies[i])
+	}
+	return advice
+}
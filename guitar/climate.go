@@ -0,0 +1,106 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package guitar
+
+import "math"
+
+// Metric is the quantity that the gradient of a Levels is expressed in.
+// Each metric is derived from a temperature in °C and relative humidity in %.
+type Metric int
+
+const (
+	RelativeHumidity    Metric = iota // Relative humidity in %
+	EquilibriumMoisture               // Wood equilibrium moisture content in %
+)
+
+// Value returns the metric for temperature t and relative humidity rh.
+func (m Metric) Value(t, rh float32) float32 {
+	switch m {
+	case EquilibriumMoisture:
+		return EMC(t, rh)
+	default:
+		return rh
+	}
+}
+
+// Humidity returns the relative humidity at temperature t that results in
+// the metric having the value v. This is the inverse of Value.
+func (m Metric) Humidity(t, v float32) float32 {
+	switch m {
+	case EquilibriumMoisture:
+		return EMCHumidity(t, v)
+	default:
+		return v
+	}
+}
+
+// Unit returns the unit of the metric, as it should follow a number.
+func (m Metric) Unit() string {
+	switch m {
+	case EquilibriumMoisture:
+		return "% wood moisture content"
+	default:
+		return "% relative humidity"
+	}
+}
+
+// DewPoint returns the dew point in °C for temperature t in °C and relative
+// humidity rh in %, using the Magnus formula.
+func DewPoint(t, rh float32) float32 {
+	const a, b = 17.62, 243.12
+	if rh <= 0 {
+		return float32(math.Inf(-1))
+	}
+	g := math.Log(float64(rh)/100) + a*float64(t)/(b+float64(t))
+	return float32(b * g / (a - g))
+}
+
+// AbsoluteHumidity returns the mass of water vapour in g/m³ for temperature t
+// in °C and relative humidity rh in %.
+func AbsoluteHumidity(t, rh float32) float32 {
+	tf := float64(t)
+	p := 6.112 * math.Exp(17.67*tf/(tf+243.5)) // saturation vapour pressure in hPa
+	return float32(p * float64(rh) * 2.1674 / (273.15 + tf))
+}
+
+// EMC returns the equilibrium moisture content of wood in % for temperature t
+// in °C and relative humidity rh in %, using the Hailwood-Horrobin equation
+// as given in the Wood Handbook of the USDA Forest Products Laboratory.
+func EMC(t, rh float32) float32 {
+	T := float64(t)*9/5 + 32
+	h := float64(rh) / 100
+	if h <= 0 {
+		return 0
+	}
+	if h > 1 {
+		h = 1
+	}
+
+	W := 330 + 0.452*T + 0.00415*T*T
+	K := 0.791 + 0.000463*T - 0.000000844*T*T
+	K1 := 6.34 + 0.000775*T - 0.0000935*T*T
+	K2 := 1.09 + 0.0284*T - 0.0000904*T*T
+
+	kh := K * h
+	return float32(1800 / W * (kh/(1-kh) + (K1*kh+2*K1*K2*kh*kh)/(1+K1*kh+K1*K2*kh*kh)))
+}
+
+// EMCHumidity returns the relative humidity in % at which wood reaches the
+// equilibrium moisture content emc at temperature t in °C.
+func EMCHumidity(t, emc float32) float32 {
+	low, high := float32(0), float32(100)
+	if emc >= EMC(t, high) {
+		return high
+	}
+	for high-low > 0.01 {
+		mid := (low + high) / 2
+		if EMC(t, mid) < emc {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
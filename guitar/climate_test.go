@@ -0,0 +1,87 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package guitar
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b, tolerance float32) bool {
+	return math.Abs(float64(a-b)) <= float64(tolerance)
+}
+
+func TestDewPoint(t *testing.T) {
+	for _, c := range []struct{ t, rh, want float32 }{
+		{20, 100, 20},
+		{20, 50, 9.3},
+		{25, 60, 16.7},
+		{0, 80, -3.0},
+		{-10, 70, -14.4}, // over water, not ice
+	} {
+		if d := DewPoint(c.t, c.rh); !near(d, c.want, 0.1) {
+			t.Errorf("DewPoint(%.1f, %.1f) = %.2f, want %.1f", c.t, c.rh, d, c.want)
+		}
+	}
+	if d := DewPoint(20, 0); !math.IsInf(float64(d), -1) {
+		t.Errorf("DewPoint of dry air = %v, want -Inf", d)
+	}
+}
+
+func TestAbsoluteHumidity(t *testing.T) {
+	for _, c := range []struct{ t, rh, want float32 }{
+		{20, 100, 17.3},
+		{25, 50, 11.5},
+		{0, 100, 4.85},
+		{20, 0, 0},
+	} {
+		if a := AbsoluteHumidity(c.t, c.rh); !near(a, c.want, 0.05) {
+			t.Errorf("AbsoluteHumidity(%.1f, %.1f) = %.2f, want %.2f", c.t, c.rh, a, c.want)
+		}
+	}
+}
+
+func TestEMC(t *testing.T) {
+	// Values from the table of the Wood Handbook at 70°F.
+	for _, c := range []struct{ rh, want float32 }{
+		{0, 0},
+		{30, 6.2},
+		{50, 9.2},
+		{65, 12.0},
+		{80, 16.0},
+	} {
+		if e := EMC(21.1, c.rh); !near(e, c.want, 0.2) {
+			t.Errorf("EMC(21.1, %.0f) = %.2f, want %.1f", c.rh, e, c.want)
+		}
+	}
+	if EMC(21, 120) != EMC(21, 100) {
+		t.Error("EMC is not limited to saturation")
+	}
+	if EMC(21, -5) != 0 {
+		t.Error("EMC of negative humidity is not zero")
+	}
+	if EMC(35, 50) >= EMC(10, 50) {
+		t.Error("EMC does not fall with the temperature")
+	}
+}
+
+func TestEMCHumidity(t *testing.T) {
+	for _, temp := range []float32{5, 21, 35} {
+		for _, rh := range []float32{10, 35, 50, 75, 95} {
+			if h := EMCHumidity(temp, EMC(temp, rh)); !near(h, rh, 0.05) {
+				t.Errorf("EMCHumidity(%.0f, EMC(%.0f, %.0f)) = %.2f", temp, temp, rh, h)
+			}
+		}
+	}
+	if h := EMCHumidity(21, 40); h != 100 {
+		t.Errorf("EMCHumidity beyond saturation = %.2f, want 100", h)
+	}
+	if v := EquilibriumMoisture.Humidity(21, EquilibriumMoisture.Value(21, 45)); !near(v, 45, 0.05) {
+		t.Errorf("Humidity is not the inverse of Value: %.2f", v)
+	}
+	if v := RelativeHumidity.Value(21, 45); v != 45 {
+		t.Errorf("RelativeHumidity.Value = %.2f, want 45", v)
+	}
+}
@@ -4,6 +4,11 @@
 
 package guitar
 
+import (
+	"fmt"
+	"strings"
+)
+
 type Danger int
 
 const (
@@ -34,10 +39,50 @@ func (d Danger) String() string {
 	}
 }
 
+// ParseDanger returns the danger with the given name; the case of the name
+// is ignored, so both "high" and "HIGH" result in High.
+func ParseDanger(s string) (Danger, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	for d := Low; d <= Extreme; d++ {
+		if strings.ToLower(d.String()) == name {
+			return d, nil
+		}
+	}
+	return Low, fmt.Errorf("unknown danger %q", s)
+}
+
+// MarshalText implements encoding.TextMarshaler, which also makes the
+// danger appear as a lower-case string in JSON and TOML.
+func (d Danger) MarshalText() ([]byte, error) {
+	if d < Low || d > Extreme {
+		return nil, fmt.Errorf("invalid danger %d", int(d))
+	}
+	return []byte(strings.ToLower(d.String())), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseDanger.
+func (d *Danger) UnmarshalText(text []byte) error {
+	v, err := ParseDanger(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// Levels describes how dangerous the climate is for a humidity-sensitive
+// item, such as a guitar, by dividing a metric into bands of danger.
 type Levels struct {
+	// Description is a short human readable description of the profile.
+	Description string
+
+	Metric   Metric
 	Gradient []float32
 	Risk     []Danger
 	Details  *Notification
+
+	// Translations contains the details in other languages, by locale.
+	Translations map[string]*Notification
 }
 
 type Notification struct {
@@ -45,13 +90,81 @@ type Notification struct {
 	BodyTmpl     string
 	ShortEffects []string
 	LongEffects  []string
+	Remedies     []string
+	Outlook      Outlook
+
+	// Dangers and Units contain the names of each Danger and the units
+	// of each Metric, in case they differ from the English defaults.
+	Dangers []string
+	Units   []string
 }
 
 func (l Levels) Threat(v float32) Danger {
+	i := l.Band(v)
+	if i == len(l.Gradient) {
+		return Extreme
+	}
+	return l.Risk[i]
+}
+
+// Assess returns the danger for temperature t and relative humidity rh,
+// by computing the metric of l from them.
+func (l Levels) Assess(t, rh float32) Danger {
+	return l.Threat(l.Metric.Value(t, rh))
+}
+
+// Band returns the index of the band that v falls into.
+// If v is beyond the last gradient, len(l.Gradient) is returned.
+func (l Levels) Band(v float32) int {
 	for i, g := range l.Gradient {
 		if v < g {
-			return l.Risk[i]
+			return i
+		}
+	}
+	return len(l.Gradient)
+}
+
+// Bounds returns the lower and upper limit of the i-th band. The last band,
+// beyond the last gradient, has no upper limit, so high is equal to low.
+func (l Levels) Bounds(i int) (low, high float32) {
+	if i > 0 {
+		low = l.Gradient[i-1]
+	}
+	if i < len(l.Gradient) {
+		high = l.Gradient[i]
+	} else {
+		high = low
+	}
+	return low, high
+}
+
+// Safe returns the lower and upper limit of the range where there is
+// no danger. If there is no such range, both limits are zero.
+func (l Levels) Safe() (low, high float32) {
+	first := -1
+	for i, r := range l.Risk {
+		if r == Low {
+			if first < 0 {
+				first = i
+			}
+			_, high = l.Bounds(i)
+		} else if first >= 0 {
+			break
 		}
 	}
-	return Extreme
+	if first < 0 {
+		return 0, 0
+	}
+	low, _ = l.Bounds(first)
+	return low, high
+}
+
+// SafeHumidity returns the range of relative humidity at temperature t
+// where there is no danger.
+func (l Levels) SafeHumidity(t float32) (low, high float32) {
+	low, high = l.Safe()
+	if low == 0 && high == 0 {
+		return 0, 0
+	}
+	return l.Metric.Humidity(t, low), l.Metric.Humidity(t, high)
 }
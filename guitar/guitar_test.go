@@ -0,0 +1,62 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package guitar
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseDanger(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		want Danger
+		err  bool
+	}{
+		{s: "low", want: Low},
+		{s: "moderate", want: Moderate},
+		{s: "Elevated", want: Elevated},
+		{s: "high", want: High},
+		{s: "HIGH", want: High},
+		{s: " severe ", want: Severe},
+		{s: "extreme", want: Extreme},
+		{s: "", err: true},
+		{s: "n/a", err: true},
+		{s: "3", err: true},
+		{s: "highest", err: true},
+	} {
+		d, err := ParseDanger(c.s)
+		if (err != nil) != c.err {
+			t.Errorf("ParseDanger(%q) error %v, want error %v", c.s, err, c.err)
+		} else if !c.err && d != c.want {
+			t.Errorf("ParseDanger(%q) = %v, want %v", c.s, d, c.want)
+		}
+	}
+}
+
+func TestDangerText(t *testing.T) {
+	for d := Low; d <= Extreme; d++ {
+		bs, err := d.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText(%v): %v", d, err)
+		}
+		var e Danger
+		if err := e.UnmarshalText(bs); err != nil || e != d {
+			t.Errorf("UnmarshalText(%q) = %v, %v, want %v", bs, e, err, d)
+		}
+	}
+	if _, err := Danger(Extreme + 1).MarshalText(); err == nil {
+		t.Error("MarshalText of an invalid danger does not fail")
+	}
+
+	var v struct{ Min Danger }
+	if err := json.Unmarshal([]byte(`{"Min": "HIGH"}`), &v); err != nil || v.Min != High {
+		t.Errorf("json.Unmarshal = %v, %v, want HIGH", v.Min, err)
+	}
+	e := Severe
+	if err := e.UnmarshalText([]byte("dire")); err == nil || e != Severe {
+		t.Errorf("UnmarshalText of an unknown danger = %v, %v, want an error and no change", e, err)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package guitar
+
+import "fmt"
+
+// The following profiles are for other items that are just as sensitive
+// to humidity as guitars are. They share the same generic templates.
+
+var Piano = Levels{
+	Description: "upright or grand piano",
+	Gradient:    []float32{30, 35, 40, 50, 60, 70, 100},
+	Risk:        []Danger{High, Elevated, Moderate, Low, Moderate, Elevated, High},
+	Details: itemNotification("piano",
+		[]string{
+			`The soundboard shrinks and the piano will go flat and out of tune quickly.`,
+			`Tuning pins may loosen and the piano will drift out of tune.`,
+			`The piano may slowly go flat, but no damage should occur with limited exposure.`,
+			`No problem will occur in this range.`,
+			`The piano may slowly go sharp, but no damage should occur with limited exposure.`,
+			`Keys and action parts swell and may become sluggish or stick; the piano goes sharp.`,
+			`Keys stick, hammers swell and the strings and tuning pins begin to rust.`,
+			`Water condenses on the piano; keys and action parts swell and stick.`,
+		},
+		[]string{
+			`The soundboard and bridges may crack, glue joints loosen and tuning pins no
+longer hold the tuning.`,
+			`The soundboard may crack and the piano will not hold its tuning.`,
+			`The piano will need to be tuned more often.`,
+			`No problem will occur in this range.`,
+			`The piano will need to be tuned more often and the touch may feel heavy.`,
+			`The soundboard loses its crown, action parts swell and rust forms on the strings.`,
+			`Felts and hammers are ruined, strings and pins rust, and mould may form inside
+the piano.`,
+			`The soundboard and case may warp, glue joints fail, and mould grows throughout
+the piano.`,
+		},
+		[]string{
+			`Run a room humidifier right away, or install a climate control system in the
+piano. Keep the piano away from heaters, fireplaces and sunny windows.`,
+			`Run a room humidifier and keep doors and windows closed in cold weather.`,
+			`Consider running a room humidifier.`,
+			`Nothing needs to be done.`,
+			`Ventilate the room and consider running a dehumidifier.`,
+			`Run a dehumidifier or air conditioner in the room.`,
+			`Run a dehumidifier right away, or install a climate control system in the piano.
+Keep the piano away from outside walls and damp floors.`,
+			`Wipe off any condensation, run a dehumidifier and heat the room right away,
+and have the piano checked by a technician.`,
+		}),
+}
+
+var Violin = Levels{
+	Description: "violin, viola, cello or other bowed string instrument",
+	Gradient:    []float32{25, 35, 40, 60, 65, 75, 100},
+	Risk:        []Danger{High, Elevated, Moderate, Low, Moderate, Elevated, High},
+	Details: itemNotification("violin",
+		[]string{
+			`The pegs shrink and slip, and the instrument will not stay in tune.`,
+			`The pegs may slip and the seams may begin to open.`,
+			`No major problems should occur with limited exposure.`,
+			`No problem will occur in this range.`,
+			`No major problems should occur with limited exposure.`,
+			`The pegs swell and stick, and the sound may become dull.`,
+			`The pegs stick, the fingerboard may rise and the sound becomes dull.`,
+			`Water condenses on the instrument, the pegs stick and the varnish may cloud.`,
+		},
+		[]string{
+			`The top and back may crack, especially near the f-holes and at the edges,
+and the seams open.`,
+			`The seams open and the top may crack.`,
+			`The seams may open, which is harmless but needs to be glued.`,
+			`No problem will occur in this range.`,
+			`The sound may become dull and the instrument feel sluggish.`,
+			`Glue joints weaken, the bridge may lean and the fingerboard may come loose.`,
+			`Glue joints fail, the top may sink near the bridge, and mould may form.`,
+			`Glue joints fail, the top and back may warp, and mould grows inside.`,
+		},
+		[]string{
+			`Keep the instrument in its closed case with a humidifier right away, and
+humidify the room.`,
+			`Keep the instrument in its closed case with a humidifier.`,
+			`Keep the case closed when you are not playing, and consider a humidifier.`,
+			`Nothing needs to be done.`,
+			`Remove any humidifier from the case.`,
+			`Remove any humidifier, put a desiccant pack in the case and run a dehumidifier.`,
+			`Move the instrument to a drier room right away and run a dehumidifier.`,
+			`Wipe off any condensation, move the instrument to a drier room right away and
+have it checked by a luthier.`,
+		}),
+}
+
+var Humidor = Levels{
+	Description: "cigars in a humidor",
+	Gradient:    []float32{55, 62, 65, 72, 75, 80, 100},
+	Risk:        []Danger{High, Elevated, Moderate, Low, Moderate, Elevated, High},
+	Details: itemNotification("humidor",
+		[]string{
+			`The cigars dry out, burn too hot and taste harsh.`,
+			`The cigars begin to dry out and may burn unevenly.`,
+			`No major problems should occur with limited exposure.`,
+			`No problem will occur in this range.`,
+			`No major problems should occur with limited exposure.`,
+			`The cigars become soft, draw poorly and are hard to keep lit.`,
+			`The cigars become spongy and mould may begin to grow.`,
+			`Water condenses in the humidor and the cigars become soggy.`,
+		},
+		[]string{
+			`The wrappers crack and unravel, and the aroma of the cigars is lost for good.`,
+			`The wrappers may crack and the cigars lose their aroma.`,
+			`The cigars may lose some of their aroma.`,
+			`No problem will occur in this range.`,
+			`The cigars may swell slightly and burn unevenly.`,
+			`The wrappers may split as the cigars swell, and mould may grow.`,
+			`Mould will grow on the cigars and the humidor, and tobacco beetles may hatch.`,
+			`The cigars are ruined by mould, and the wood of the humidor may warp.`,
+		},
+		[]string{
+			`Refill or recharge the humidifier right away with distilled water or
+propylene glycol solution, and check that the lid closes tightly.`,
+			`Refill the humidifier and open the humidor less often.`,
+			`Check the humidifier and refill it if needed.`,
+			`Nothing needs to be done.`,
+			`Leave the humidifier out for a while.`,
+			`Remove the humidifier and leave the humidor open for a few hours.`,
+			`Remove the humidifier, air out the humidor and check every cigar for mould.`,
+			`Take the cigars out right away, dry the humidor and throw away any cigar with
+mould.`,
+		}),
+}
+
+var WineCellar = Levels{
+	Description: "wine cellar",
+	Gradient:    []float32{30, 40, 50, 80, 85, 90, 100},
+	Risk:        []Danger{High, Elevated, Moderate, Low, Moderate, Elevated, High},
+	Details: itemNotification("wine cellar",
+		[]string{
+			`The corks begin to dry out and shrink.`,
+			`The corks may begin to dry out.`,
+			`No major problems should occur with limited exposure.`,
+			`No problem will occur in this range.`,
+			`No major problems should occur with limited exposure.`,
+			`Labels may begin to get damp.`,
+			`Labels get damp and mould may begin to grow on the corks.`,
+			`Water condenses on the bottles and walls, and labels soak through.`,
+		},
+		[]string{
+			`The corks dry out and let air in, which oxidises the wine, and the wine
+evaporates.`,
+			`The corks may dry out and let air in, which oxidises the wine.`,
+			`Bottles stored upright may have dry corks.`,
+			`No problem will occur in this range.`,
+			`Labels may stain.`,
+			`Labels peel and stain, and mould may grow on the corks and shelves.`,
+			`Labels are ruined, mould grows throughout the cellar and can taint the wine.`,
+			`Mould covers the cellar and the corks, and can taint the wine.`,
+		},
+		[]string{
+			`Run a humidifier in the cellar right away, or put out trays of water or
+damp gravel.`,
+			`Run a humidifier in the cellar and store all bottles lying down.`,
+			`Store all bottles lying down so the corks stay moist.`,
+			`Nothing needs to be done.`,
+			`Improve the ventilation of the cellar.`,
+			`Run a dehumidifier and improve the ventilation of the cellar.`,
+			`Run a dehumidifier right away and check the cellar for leaks and mould.`,
+			`Look for standing water or a leak right away, run a dehumidifier and ventilate
+the cellar.`,
+		}),
+}
+
+// itemNotification returns a notification for item with generic templates.
+func itemNotification(item string, short, long, remedies []string) *Notification {
+	return &Notification{
+		SubjectTmpl:  fmt.Sprintf(`Your %s is in {{.Risk}} danger!`, item),
+		BodyTmpl:     fmt.Sprintf(itemBodyTmpl, item),
+		ShortEffects: short,
+		LongEffects:  long,
+		Remedies:     remedies,
+		Outlook:      Larrivee.Details.Outlook,
+	}
+}
+
+const itemBodyTmpl = `Your %s is not in the correct humidity range ({{.SafeLow}}–{{.SafeHigh}}{{.Unit}}).
+
+Currently, the environment is {{if .Above}}above {{.Low}}{{else}}in the range of {{.Low}}–{{.High}}{{end}}{{.Unit}}.
+This range is categorized as:
+
+                                    {{.Risk}}
+
+If it remains in this range, you can expect the following effects:
+
+## 1–3 Days
+
+{{.ShorttermEffects}}
+
+## 3+ Days
+
+{{.LongtermEffects}}
+
+## What You Can Do
+{{range .Advice}}
+{{.}}
+{{end}}
+
+## Humidity Trend
+
+{{.Trend}}
+`
@@ -5,14 +5,15 @@
 package guitar
 
 var Larrivee = Levels{
-	Gradient: []float32{10, 20, 25, 35, 42, 55, 70, 85, 90, 100},
-	Risk:     []Danger{Extreme, Severe, High, Elevated, Moderate, Low, Moderate, Elevated, High, Severe},
+	Description: "Larrivee acoustic guitar",
+	Gradient:    []float32{10, 20, 25, 35, 42, 55, 70, 85, 90, 100},
+	Risk:        []Danger{Extreme, Severe, High, Elevated, Moderate, Low, Moderate, Elevated, High, Severe},
 	Details: &Notification{
 		SubjectTmpl: `Your guitar is in {{.Risk}} danger!`,
 		BodyTmpl: `Your guitar is not in the correct humidity range (42–55%).
 You should make changes to the environment of the guitar to maintain this range.
 
-Currently, the environment is in the range of {{.Low}}–{{.High}} relative humidity.
+Currently, the environment is {{if .Above}}above {{.Low}}{{else}}in the range of {{.Low}}–{{.High}}{{end}}{{.Unit}}.
 This humidity range is categorized as:
 
                                     {{.Risk}}
@@ -27,6 +28,10 @@ If the guitar remains in this humidity range, you can expect the following effec
 
 {{.LongtermEffects}}
 
+## What You Can Do
+{{range .Advice}}
+{{.}}
+{{end}}
 This information is made available by Larrivee.
 See http://www.larivee.com/pdfs/Larrivee%20Care%20%20Maintenance.pdf for more information.
 
@@ -106,5 +111,51 @@ front of and behind bridge, if the glue joints do not delaminate then the
 guitar will be unplayable.  Fretboard from the 14th on will appear raised.
 Mildew may form inside the guitar. Guitar will very likely de-construct itself.`,
 		},
+		Remedies: []string{
+			`Put the guitar in its case with a humidifier pack right away and humidify
+the room. Keep the guitar away from heaters and air vents.`,
+			`Close the guitar in its case with a freshly filled humidifier pack and run
+a room humidifier.`,
+			`Keep the guitar in its closed case with a humidifier pack, and check the
+pack every day.`,
+			`Keep the guitar in its case with a humidifier pack when you are not
+playing it.`,
+			`Close the case when you are not playing the guitar, and consider using a
+humidifier pack.`,
+			`Nothing needs to be done, the guitar is fine where it is.`,
+			`Remove any humidifier pack from the case and keep the case closed when you
+are not playing the guitar.`,
+			`Remove any humidifier pack, put a desiccant pack in the case and run a
+dehumidifier in the room.`,
+			`Run a dehumidifier or air conditioner in the room, and keep the guitar in
+its closed case with desiccant packs.`,
+			`Move the guitar to a drier room right away, run a dehumidifier and keep
+desiccant packs in the closed case.`,
+			`Wipe off any condensation, move the guitar to a drier room right away and
+have it checked by a luthier once it has dried out.`,
+		},
+		Outlook: Outlook{
+			Worsening: `The humidity is moving further away from the safe range, so act right away.`,
+			Improving: `The humidity is moving back towards the safe range; keep up your current
+measures until it is there.`,
+			Leaving: `The humidity is still safe, but it is moving towards the edge of the safe
+range, so keep an eye on it.`,
+		},
+	},
+	Translations: map[string]*Notification{
+		"de": larriveeDE,
 	},
 }
+
+// LarriveeEMC is equivalent to Larrivee, but expresses the levels in terms
+// of the equilibrium moisture content of the wood. The gradient corresponds
+// to that of Larrivee at room temperature (21°C).
+var LarriveeEMC = Levels{
+	Description: "Larrivee acoustic guitar, by wood moisture content",
+	Metric:      EquilibriumMoisture,
+	Gradient:    []float32{2.5, 4.5, 5.5, 7, 8, 10, 13, 18, 20.5, 30},
+	Risk:        Larrivee.Risk,
+	Details:     Larrivee.Details,
+
+	Translations: Larrivee.Translations,
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package guitar
+
+// larriveeDE is the German translation of the details of Larrivee.
+var larriveeDE = &Notification{
+	SubjectTmpl: `Gefahr für Ihre Gitarre: {{.RiskName}}!`,
+	BodyTmpl: `Ihre Gitarre befindet sich nicht im richtigen Luftfeuchtigkeitsbereich (42–55%).
+Sie sollten die Umgebung der Gitarre so verändern, dass dieser Bereich eingehalten wird.
+
+Derzeit liegt die Umgebung {{if .Above}}über {{.Low}}{{else}}im Bereich von {{.Low}}–{{.High}}{{end}}{{.Unit}}.
+Dieser Bereich wird eingestuft als:
+
+                                    {{.RiskName}}
+
+Bleibt die Gitarre in diesem Bereich, ist mit den folgenden Auswirkungen zu rechnen:
+
+## 1–3 Tage
+
+{{.ShorttermEffects}}
+
+## Mehr als 3 Tage
+
+{{.LongtermEffects}}
+
+## Was Sie tun können
+{{range .Advice}}
+{{.}}
+{{end}}
+Diese Informationen werden von Larrivee zur Verfügung gestellt.
+Weitere Informationen finden Sie unter
+http://www.larivee.com/pdfs/Larrivee%20Care%20%20Maintenance.pdf (Englisch).
+
+
+## Luftfeuchtigkeitsverlauf
+
+Mit dem folgenden Diagramm des Luftfeuchtigkeitsverlaufs können Sie die Ursache
+des Problems eingrenzen.
+
+{{.Trend}}
+
+Viele Grüße
+
+Ben Morgan
+`,
+	ShortEffects: []string{
+		`Die Bundenden fühlen sich scharf an, Decke und Boden fallen ein (konkav), die
+Saitenlage sinkt sehr schnell und die Gitarre beginnt zu schnarren. In der Decke
+können Risse entstehen, besonders vom Steg zum Gitarrenende hin; der Steg kann
+abreißen.`,
+		`Die Bundenden fühlen sich wahrscheinlich scharf an, Decke und Boden fallen
+wahrscheinlich ein (konkav), die Saitenlage sinkt sehr schnell und die Gitarre
+beginnt wahrscheinlich zu schnarren. In der Decke kann ein Riss vom Steg zum
+Gitarrenende hin entstehen; der Steg kann sich lösen.`,
+		`Die Bundenden fühlen sich scharf an, die Decke kann beginnen einzufallen
+(konkav), die Saitenlage sinkt sehr schnell und die Gitarre kann zu schnarren
+beginnen.`,
+		`Die Bundenden können sich scharf anfühlen, die Decke kann leicht einfallen
+(konkav).`,
+		`Bei kurzer Dauer sollten keine größeren Probleme auftreten.`,
+		`In diesem Bereich treten keine Probleme auf.`,
+		`Bei kurzer Dauer sollten keine größeren Probleme auftreten.`,
+		`Der Klang kann leiden. Die Decke kann aufgequollen wirken. Die Saitenlage kann
+etwas zu hoch sein.`,
+		`Der Korpus kann aufgequollen wirken, der Klang leidet etwas und die Bespielbarkeit
+kann abnehmen. Die Saitenlage wird höher.`,
+		`Der Korpus wirkt aufgequollen, der Klang leidet und die Bespielbarkeit nimmt ab.
+Die Bodenbalken können sich lösen, da sich das Holz ausdehnt. Die Saitenlage wird
+schnell sehr hoch.`,
+	},
+	LongEffects: []string{
+		`Die Bundenden fühlen sich sehr scharf an, Decke und Boden fallen ein (konkav),
+die letzten sechs Bünde des Griffbretts sinken in das Schallloch ein, die
+Saitenlage ist extrem niedrig und die Gitarre schnarrt auf dem ganzen Griffbrett.
+Die Stegflügel wirken konkav, in der Decke entstehen Risse, besonders vom Steg
+zum Gitarrenende hin, und der Steg reißt ab. Rosette und Endkeil stehen hervor.
+Balken, die sich nicht lösen, können die Randeinlage herausdrücken; die Balken
+zeichnen sich als Erhebungen auf Decke und Boden ab.`,
+		`Die Bundenden fühlen sich sehr scharf an, Decke und Boden sind eingefallen
+(konkav), die letzten sechs Bünde des Griffbretts sinken in das Schallloch ein,
+die Saitenlage sinkt und die Gitarre schnarrt. Die Stegflügel wirken konkav, in
+der Decke entsteht wahrscheinlich ein großer Riss vom Steg zum Gitarrenende hin
+und der Steg kann sich lösen. Rosette und Endkeil können sichtbar hervorstehen.`,
+		`Die Bundenden fühlen sich wahrscheinlich sehr scharf an, Decke und Boden werden
+flach oder fallen ein (konkav), die letzten sechs Bünde des Griffbretts sinken
+wahrscheinlich in das Schallloch ein, die Saitenlage sinkt und die Gitarre
+schnarrt. Die Stegflügel wirken konkav, in der Decke können Risse entstehen,
+besonders vom Steg zum Gitarrenende hin, und der Steg kann abreißen (sich lösen).`,
+		`Die Bundenden fühlen sich scharf an, Decke und Boden werden flach oder fallen
+ein (konkav), die Saitenlage fühlt sich niedriger an und die Gitarre schnarrt
+wahrscheinlich. Die Stegflügel wirken konkav; nach einigen Monaten kann sich der
+Steg anheben oder abreißen.`,
+		`Die Bundenden können sich scharf anfühlen, die Decke kann leicht eingefallen
+wirken (konkav), die Saitenlage kann etwas sinken, die Stegflügel wirken konkav
+und die Gitarre kann zu schnarren beginnen.`,
+		`In diesem Bereich treten keine Probleme auf.`,
+		`Decke und Boden wölben sich nach außen (konvex), die Bespielbarkeit leidet.
+Das Griffbrett kann sich ab dem 14. Bund anheben. Nach einigen Monaten beginnt
+die Gitarre muffig zu riechen.`,
+		`Decke und Boden wölben sich nach außen (konvex), die Bespielbarkeit leidet.
+Das Griffbrett kann sich ab dem 14. Bund anheben. Nach einigen Monaten beginnt
+die Gitarre muffig zu riechen.`,
+		`Nach einigen Wochen lösen sich die Balken, Decke und Boden wölben sich stark
+nach außen (konvex), der Steg kann sich lockern oder abreißen und die
+Bespielbarkeit leidet. Das Griffbrett hebt sich ab dem 14. Bund an. Im Inneren
+der Gitarre kann sich Schimmel bilden.`,
+		`Alle Leimverbindungen lockern sich, die Balken von Decke und Boden lösen sich.
+Der Steg kann von der Decke abreißen, die Decke dehnt sich aus und wölbt sich vor
+und hinter dem Steg nach außen (konvex); lösen sich die Leimverbindungen nicht,
+ist die Gitarre trotzdem unbespielbar. Das Griffbrett hebt sich ab dem 14. Bund
+an. Im Inneren der Gitarre kann sich Schimmel bilden. Die Gitarre wird sich sehr
+wahrscheinlich selbst zerlegen.`,
+	},
+	Remedies: []string{
+		`Legen Sie die Gitarre sofort mit einem Befeuchter in ihren Koffer und befeuchten
+Sie den Raum. Halten Sie die Gitarre von Heizkörpern und Lüftungsschlitzen fern.`,
+		`Schließen Sie die Gitarre mit einem frisch befüllten Befeuchter in ihrem Koffer
+ein und lassen Sie einen Raumluftbefeuchter laufen.`,
+		`Bewahren Sie die Gitarre mit einem Befeuchter im geschlossenen Koffer auf und
+prüfen Sie den Befeuchter täglich.`,
+		`Bewahren Sie die Gitarre mit einem Befeuchter im Koffer auf, wenn Sie nicht
+darauf spielen.`,
+		`Schließen Sie den Koffer, wenn Sie nicht auf der Gitarre spielen, und ziehen Sie
+einen Befeuchter in Betracht.`,
+		`Es ist nichts zu tun, die Gitarre ist dort, wo sie ist, gut aufgehoben.`,
+		`Nehmen Sie einen etwaigen Befeuchter aus dem Koffer und halten Sie den Koffer
+geschlossen, wenn Sie nicht auf der Gitarre spielen.`,
+		`Nehmen Sie einen etwaigen Befeuchter heraus, legen Sie einen Trockenbeutel in
+den Koffer und lassen Sie einen Luftentfeuchter im Raum laufen.`,
+		`Lassen Sie einen Luftentfeuchter oder eine Klimaanlage im Raum laufen und
+bewahren Sie die Gitarre mit Trockenbeuteln im geschlossenen Koffer auf.`,
+		`Bringen Sie die Gitarre sofort in einen trockeneren Raum, lassen Sie einen
+Luftentfeuchter laufen und legen Sie Trockenbeutel in den geschlossenen Koffer.`,
+		`Wischen Sie jegliches Kondenswasser ab, bringen Sie die Gitarre sofort in einen
+trockeneren Raum und lassen Sie sie von einem Gitarrenbauer prüfen, sobald sie
+getrocknet ist.`,
+	},
+	Outlook: Outlook{
+		Worsening: `Die Luftfeuchtigkeit entfernt sich weiter vom sicheren Bereich, handeln Sie
+also sofort.`,
+		Improving: `Die Luftfeuchtigkeit bewegt sich zurück in Richtung des sicheren Bereichs;
+behalten Sie Ihre Maßnahmen bei, bis sie dort angekommen ist.`,
+		Leaving: `Die Luftfeuchtigkeit ist noch sicher, bewegt sich aber auf den Rand des
+sicheren Bereichs zu, behalten Sie sie also im Auge.`,
+	},
+	Dangers: []string{"gering", "mäßig", "erhöht", "HOCH", "SCHWER", "EXTREM"},
+	Units:   []string{"% relative Luftfeuchtigkeit", "% Holzfeuchte"},
+}
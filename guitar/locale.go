@@ -0,0 +1,90 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package guitar
+
+import "strings"
+
+// Localize returns a copy of l with the details translated for locale,
+// which may be given as "de", "de-AT" or "de_AT.UTF-8". If there is no
+// translation for the locale or its language, l is returned unchanged,
+// which means falling back to English. The same holds for every text
+// that the translation leaves out.
+func (l Levels) Localize(locale string) Levels {
+	for _, k := range localeKeys(locale) {
+		if n, ok := l.Translations[k]; ok {
+			l.Details = merge(l.Details, n)
+			return l
+		}
+	}
+	return l
+}
+
+// merge returns the translation tr, with any text that it leaves out
+// taken from en instead.
+func merge(en, tr *Notification) *Notification {
+	if en == nil || tr == nil {
+		return tr
+	}
+	return &Notification{
+		SubjectTmpl:  mergeString(en.SubjectTmpl, tr.SubjectTmpl),
+		BodyTmpl:     mergeString(en.BodyTmpl, tr.BodyTmpl),
+		ShortEffects: mergeStrings(en.ShortEffects, tr.ShortEffects),
+		LongEffects:  mergeStrings(en.LongEffects, tr.LongEffects),
+		Remedies:     mergeStrings(en.Remedies, tr.Remedies),
+		Outlook: Outlook{
+			Worsening: mergeString(en.Outlook.Worsening, tr.Outlook.Worsening),
+			Improving: mergeString(en.Outlook.Improving, tr.Outlook.Improving),
+			Leaving:   mergeString(en.Outlook.Leaving, tr.Outlook.Leaving),
+		},
+		Dangers: mergeStrings(en.Dangers, tr.Dangers),
+		Units:   mergeStrings(en.Units, tr.Units),
+	}
+}
+
+func mergeString(en, tr string) string {
+	if tr == "" {
+		return en
+	}
+	return tr
+}
+
+func mergeStrings(en, tr []string) []string {
+	n := len(en)
+	if len(tr) > n {
+		n = len(tr)
+	}
+	if n == 0 {
+		return nil
+	}
+	xs := make([]string, n)
+	for i := range xs {
+		if i < len(en) {
+			xs[i] = en[i]
+		}
+		if i < len(tr) && tr[i] != "" {
+			xs[i] = tr[i]
+		}
+	}
+	return xs
+}
+
+// localeKeys returns the keys that are looked up for locale, from the
+// most to the least specific; "de_AT.UTF-8" results in "de_at" and "de".
+func localeKeys(locale string) []string {
+	locale = strings.ToLower(locale)
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	locale = strings.Replace(locale, "-", "_", -1)
+	if locale == "" {
+		return nil
+	}
+
+	keys := []string{locale}
+	if i := strings.Index(locale, "_"); i >= 0 {
+		keys = append(keys, locale[:i])
+	}
+	return keys
+}
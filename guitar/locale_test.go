@@ -0,0 +1,40 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package guitar
+
+import "testing"
+
+func TestLocalizeFallback(t *testing.T) {
+	l := Larrivee
+	l.Translations = map[string]*Notification{
+		"de": {
+			SubjectTmpl: "Gefahr: {{.RiskName}}",
+			Remedies:    []string{"", "Befeuchten"},
+			Dangers:     []string{"gering"},
+		},
+	}
+	de := l.Localize("de_DE.UTF-8")
+	if de.Details.SubjectTmpl != "Gefahr: {{.RiskName}}" {
+		t.Errorf("subject not translated: %q", de.Details.SubjectTmpl)
+	}
+	if de.Details.BodyTmpl != Larrivee.Details.BodyTmpl {
+		t.Error("missing body does not fall back to English")
+	}
+	if de.Details.Remedies[0] != Larrivee.Details.Remedies[0] || de.Details.Remedies[1] != "Befeuchten" {
+		t.Errorf("remedies not merged: %q", de.Details.Remedies[:2])
+	}
+	if de.Details.Outlook != Larrivee.Details.Outlook {
+		t.Error("missing outlook does not fall back to English")
+	}
+	if got := de.DangerName(Low); got != "gering" {
+		t.Errorf("DangerName(Low) = %q", got)
+	}
+	if got := de.DangerName(High); got != "HIGH" {
+		t.Errorf("DangerName(High) = %q, want English fallback", got)
+	}
+	if Larrivee.Details.SubjectTmpl == de.Details.SubjectTmpl {
+		t.Error("English details modified")
+	}
+}
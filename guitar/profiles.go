@@ -0,0 +1,41 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package guitar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Profiles contains all built-in levels by their lower-case name.
+var Profiles = map[string]Levels{
+	"larrivee":     Larrivee,
+	"larrivee-emc": LarriveeEMC,
+	"piano":        Piano,
+	"violin":       Violin,
+	"humidor":      Humidor,
+	"wine-cellar":  WineCellar,
+}
+
+// ProfileNames returns the names of all built-in profiles in sorted order.
+func ProfileNames() []string {
+	names := make([]string, 0, len(Profiles))
+	for k := range Profiles {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the built-in levels with the given name;
+// the case of the name is ignored.
+func Profile(name string) (Levels, error) {
+	l, ok := Profiles[strings.ToLower(name)]
+	if !ok {
+		return Levels{}, fmt.Errorf("unknown profile %q", name)
+	}
+	return l, nil
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package guitar
+
+import "testing"
+
+// TestProfileBands checks that every profile has a remedy for each of its
+// bands, in each of its translations.
+func TestProfileBands(t *testing.T) {
+	for name, l := range Profiles {
+		// The band beyond the last gradient is always extreme.
+		bands := len(l.Gradient) + 1
+		if len(l.Risk) != len(l.Gradient) {
+			t.Errorf("%s: %d risks for %d gradients", name, len(l.Risk), len(l.Gradient))
+		}
+		details := map[string]*Notification{"en": l.Details}
+		for k, n := range l.Translations {
+			details[k] = n
+		}
+		for locale, n := range details {
+			if n == nil {
+				continue
+			}
+			if len(n.Remedies) != bands {
+				t.Errorf("%s (%s): %d remedies for %d bands", name, locale, len(n.Remedies), bands)
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package guitar
+
+import (
+	"bytes"
+	"errors"
+	"text/template"
+)
+
+// Report contains the data that is made available to the notification
+// templates SubjectTmpl and BodyTmpl.
+//
+// The current band ranges from Low to High, unless Above is true, in which
+// case it is the last band and open-ended, so that High is meaningless.
+type Report struct {
+	Risk             Danger
+	RiskName         string
+	Low              float32
+	High             float32
+	Above            bool
+	SafeLow          float32
+	SafeHigh         float32
+	Unit             string
+	ShorttermEffects string
+	LongtermEffects  string
+	Direction        Direction
+	Advice           []string
+	Trend            string
+}
+
+// Report returns the template data for the band that v falls into;
+// v is expressed in the metric of l and is moving in the direction dir.
+// The trend is passed through as is, so it should already be formatted.
+func (l Levels) Report(v float32, dir Direction, trend string) Report {
+	i := l.Band(v)
+	r := Report{
+		Risk:      l.Threat(v),
+		Unit:      l.Metric.Unit(),
+		Direction: dir,
+		Advice:    l.Recommend(v, dir),
+		Trend:     trend,
+	}
+	r.RiskName = l.DangerName(r.Risk)
+	r.Low, r.High = l.Bounds(i)
+	r.Above = i == len(l.Gradient)
+	r.SafeLow, r.SafeHigh = l.Safe()
+	if l.Details != nil {
+		if int(l.Metric) < len(l.Details.Units) {
+			r.Unit = l.Details.Units[l.Metric]
+		}
+		if i < len(l.Details.ShortEffects) {
+			r.ShorttermEffects = l.Details.ShortEffects[i]
+		}
+		if i < len(l.Details.LongEffects) {
+			r.LongtermEffects = l.Details.LongEffects[i]
+		}
+	}
+	return r
+}
+
+// DangerName returns the name of d in the language of the details of l.
+func (l Levels) DangerName(d Danger) string {
+	if l.Details != nil && d >= 0 && int(d) < len(l.Details.Dangers) {
+		return l.Details.Dangers[d]
+	}
+	return d.String()
+}
+
+// Render executes the subject and body templates of n with the data in r.
+func (n *Notification) Render(r Report) (subject, body string, err error) {
+	if n == nil {
+		return "", "", errors.New("notification undefined")
+	}
+
+	subject, err = execute("subject", n.SubjectTmpl, r)
+	if err != nil {
+		return "", "", err
+	}
+	body, err = execute("body", n.BodyTmpl, r)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+func execute(name, text string, data interface{}) (string, error) {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = t.Execute(&buf, data)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
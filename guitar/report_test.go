@@ -0,0 +1,57 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package guitar
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+const testTrend = `  60 |
+  50 | ****
+  40 |     ****
+     +---------
+      -2h  now`
+
+// TestLarriveeReport renders the Larrivee notification for a humidity in
+// every band and compares it with the golden file of the band.
+func TestLarriveeReport(t *testing.T) {
+	for i := 0; i <= len(Larrivee.Gradient); i++ {
+		low, high := Larrivee.Bounds(i)
+		v := (low + high) / 2
+		if i == len(Larrivee.Gradient) {
+			v = low + 1
+		}
+		dir := Falling
+		if v >= 50 {
+			dir = Rising
+		}
+
+		subject, body, err := Larrivee.Details.Render(Larrivee.Report(v, dir, testTrend))
+		if err != nil {
+			t.Fatalf("band %d: %s", i, err)
+		}
+		got := []byte(subject + "\n\n" + body)
+
+		golden := filepath.Join("testdata", fmt.Sprintf("larrivee-band-%02d.golden", i))
+		if *update {
+			if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("band %d: %s (run go test -update to create it)", i, err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("band %d: report differs from %s:\n%s", i, golden, got)
+		}
+	}
+}
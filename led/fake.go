@@ -0,0 +1,44 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package led
+
+import (
+	"sync"
+
+	"github.com/kidoman/embd"
+)
+
+// FakePin is a Pin that is not connected to any hardware. It remembers
+// the value last written and how often it was toggled, so that LED
+// patterns can be run and checked without a Raspberry Pi.
+type FakePin struct {
+	mu      sync.Mutex
+	value   int
+	toggles int
+}
+
+func (p *FakePin) Write(val int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if val != p.value {
+		p.toggles++
+	}
+	p.value = val
+	return nil
+}
+
+// On returns true if the last value written was embd.High.
+func (p *FakePin) On() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.value == embd.High
+}
+
+// Toggles returns the number of times the value of the pin changed.
+func (p *FakePin) Toggles() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.toggles
+}
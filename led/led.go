@@ -7,16 +7,26 @@ package led
 import (
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/kidoman/embd"
 	_ "github.com/kidoman/embd/host/rpi"
 )
 
+// Pin is the part of embd.DigitalPin that an LED needs.
+type Pin interface {
+	Write(val int) error
+}
+
 type LED struct {
-	pin   embd.DigitalPin
+	pin   Pin
 	state bool
 
+	// mu serializes Blink and Stop, so that only one blinking goroutine
+	// runs at a time, which is stopped by closing stop and has finished
+	// once ok is closed.
+	mu    sync.Mutex
 	blink bool
 	ok    chan struct{}
 	stop  chan struct{}
@@ -32,6 +42,12 @@ func New(pin int) *LED {
 	return &LED{pin: p}
 }
 
+// NewWithPin returns an LED that is controlled through p,
+// which may for example be a FakePin.
+func NewWithPin(p Pin) *LED {
+	return &LED{pin: p}
+}
+
 func (l *LED) On() {
 	l.pin.Write(embd.High)
 	l.state = true
@@ -65,12 +81,13 @@ func (l *LED) Blink(pattern ...time.Duration) {
 		return
 	}
 
-	l.Stop()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stopBlink()
+	l.blink = true
+	stop, ok := make(chan struct{}), make(chan struct{})
+	l.stop, l.ok = stop, ok
 	go func() {
-		l.blink = true
-		l.stop = make(chan struct{})
-		l.ok = make(chan struct{})
-
 		l.On()
 	outer:
 		for {
@@ -78,18 +95,25 @@ func (l *LED) Blink(pattern ...time.Duration) {
 				select {
 				case <-time.After(t):
 					l.Toggle()
-				case <-l.stop:
+				case <-stop:
 					break outer
 				}
 			}
 		}
 
 		l.Off()
-		close(l.ok)
+		close(ok)
 	}()
 }
 
 func (l *LED) Stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stopBlink()
+}
+
+// stopBlink stops the blinking goroutine, if any, and turns the LED off.
+func (l *LED) stopBlink() {
 	if l.blink {
 		close(l.stop)
 		<-l.ok
@@ -0,0 +1,31 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package led
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlinkBackToBack(t *testing.T) {
+	p := &FakePin{}
+	l := NewWithPin(p)
+	done := make(chan struct{})
+	go func() {
+		l.Blink(time.Millisecond)
+		close(done)
+	}()
+	l.Blink(time.Millisecond, 2*time.Millisecond)
+	<-done
+	time.Sleep(10 * time.Millisecond)
+
+	// Every blinking goroutine must have been stopped.
+	l.Stop()
+	n := p.Toggles()
+	time.Sleep(10 * time.Millisecond)
+	if p.On() || p.Toggles() != n {
+		t.Error("LED still blinks after Stop")
+	}
+}
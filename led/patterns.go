@@ -18,4 +18,6 @@ var (
 	High     = []time.Duration{50 * time.Millisecond, 500 * time.Millisecond}
 	Severe   = []time.Duration{50 * time.Millisecond, 250 * time.Millisecond}
 	Extreme  = []time.Duration{50 * time.Millisecond, 50 * time.Millisecond}
+
+	Failure = []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 100 * time.Millisecond, 2000 * time.Millisecond}
 )